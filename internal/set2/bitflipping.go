@@ -0,0 +1,55 @@
+package set
+
+import (
+	"bytes"
+	"crypto/rand"
+)
+
+const (
+	commentPrefix = "comment1=cooking%20MCs;userdata="
+	commentSuffix = ";comment2=%20like%20a%20pound%20of%20bacon"
+)
+
+// CommentEnvelope wraps the userdata for the bitflipping challenges and quotes
+// out ';' and '=' such that no key value pair can be injected directly.
+func CommentEnvelope(userdata []byte) []byte {
+
+	quoted := bytes.ReplaceAll(userdata, []byte(";"), []byte("%3B"))
+	quoted = bytes.ReplaceAll(quoted, []byte("="), []byte("%3D"))
+
+	envelope := append([]byte(commentPrefix), quoted...)
+	return append(envelope, commentSuffix...)
+}
+
+func IsAdminEnvelope(plaintext []byte) bool {
+
+	for _, pair := range bytes.Split(plaintext, []byte(";")) {
+		if bytes.Equal(pair, []byte("admin=true")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CBCBitflipOracleFactory returns the challenge 16 oracle, encrypt wraps
+// the userdata with CommentEnvelope and isAdmin checks the decrypted envelope.
+func CBCBitflipOracleFactory() (encrypt func([]byte) []byte, isAdmin func([]byte) bool) {
+
+	key := make([]byte, 16)
+
+	if _, err := rand.Reader.Read(key); err != nil {
+		panic("Not enough randomness")
+	}
+
+	encrypt = func(userdata []byte) []byte {
+		return CBCEncrypt(CommentEnvelope(userdata), key)
+	}
+
+	isAdmin = func(ciphertext []byte) bool {
+		plaintext, err := CBCDecrypt(ciphertext, key)
+		return err == nil && IsAdminEnvelope(plaintext)
+	}
+
+	return encrypt, isAdmin
+}
@@ -0,0 +1,67 @@
+package set
+
+const (
+	mtN         = 624
+	mtM         = 397
+	mtMatrixA   = 0x9908b0df
+	mtUpperMask = 0x80000000
+	mtLowerMask = 0x7fffffff
+)
+
+type MT19937 struct {
+	state [mtN]uint32
+	index int
+}
+
+func NewMT19937(seed uint32) *MT19937 {
+	mt := &MT19937{}
+	mt.Seed(seed)
+	return mt
+}
+
+func (mt *MT19937) Seed(seed uint32) {
+	mt.state[0] = seed
+	for i := 1; i < mtN; i++ {
+		prev := mt.state[i-1]
+		mt.state[i] = 1812433253*(prev^(prev>>30)) + uint32(i)
+	}
+	// force a twist on the first call to Uint32
+	mt.index = mtN
+}
+
+func (mt *MT19937) twist() {
+	for i := 0; i < mtN; i++ {
+		y := (mt.state[i] & mtUpperMask) | (mt.state[(i+1)%mtN] & mtLowerMask)
+		next := mt.state[(i+mtM)%mtN] ^ (y >> 1)
+		if y&1 == 1 {
+			next ^= mtMatrixA
+		}
+		mt.state[i] = next
+	}
+	mt.index = 0
+}
+
+func (mt *MT19937) Uint32() uint32 {
+	if mt.index >= mtN {
+		mt.twist()
+	}
+
+	y := mt.state[mt.index]
+	mt.index++
+
+	return Temper(y)
+}
+
+func Temper(y uint32) uint32 {
+	y ^= y >> 11
+	y ^= (y << 7) & 0x9d2c5680
+	y ^= (y << 15) & 0xefc60000
+	y ^= y >> 18
+	return y
+}
+
+// NewMT19937FromState continues a generator from 624 untempered state words,
+// the next call to Uint32 twists the given state.
+func NewMT19937FromState(state [mtN]uint32) *MT19937 {
+	return &MT19937{state: state, index: mtN}
+}
@@ -59,6 +59,15 @@ func PCKS7PaddingVarBlockLen(input []byte, blocklength int) []byte {
 
 func CBCEncrypt(input, key []byte) []byte {
 
+	iv := make([]byte, aes.BlockSize)
+	rand.Read(iv)
+
+	// the IV is the first block of the ciphertext
+	return append(iv, CBCEncryptWithIV(input, key, iv)...)
+}
+
+func CBCEncryptWithIV(input, key, iv []byte) []byte {
+
 	c, err := aes.NewCipher(key)
 
 	if err != nil {
@@ -66,22 +75,38 @@ func CBCEncrypt(input, key []byte) []byte {
 	}
 
 	blocksize := c.BlockSize()
+
+	if len(iv) != blocksize {
+		panic(fmt.Sprintf("IV size of %d does not match block size %d", len(iv), blocksize))
+	}
+
 	padded := PCKS7PaddingVarBlockLen(input, blocksize)
-	// space for IV and padding
-	ciphertext := make([]byte, len(padded)+blocksize)
-	rand.Read(ciphertext[:blocksize])
+	ciphertext := make([]byte, len(padded))
+	prev := iv
 
-	for processed := blocksize; processed < len(ciphertext); processed += blocksize {
-		prev := ciphertext[processed-blocksize : processed]
-		block := padded[processed-blocksize : processed]
+	for processed := 0; processed < len(ciphertext); processed += blocksize {
+		block := padded[processed : processed+blocksize]
 		xored := XOR(prev, block)
 		c.Encrypt(ciphertext[processed:processed+blocksize], xored)
+		prev = ciphertext[processed : processed+blocksize]
 	}
 	return ciphertext
 }
 
 func CBCDecrypt(input, key []byte) ([]byte, error) {
 
+	blocksize := aes.BlockSize
+
+	if len(input)%blocksize > 0 || len(input) < 32 {
+		panic(fmt.Sprintf("Input size of %d not a multiple of %d or to small", len(input), blocksize))
+	}
+
+	// the IV is the first block of the ciphertext
+	return CBCDecryptWithIV(input[blocksize:], key, input[:blocksize])
+}
+
+func CBCDecryptWithIV(input, key, iv []byte) ([]byte, error) {
+
 	c, err := aes.NewCipher(key)
 
 	if err != nil {
@@ -89,21 +114,26 @@ func CBCDecrypt(input, key []byte) ([]byte, error) {
 	}
 
 	blocksize := c.BlockSize()
-	plaintext := make([]byte, len(input)-blocksize)
 
-	if len(input)%blocksize > 0 || len(input) < 32 {
+	if len(iv) != blocksize {
+		panic(fmt.Sprintf("IV size of %d does not match block size %d", len(iv), blocksize))
+	}
+
+	if len(input)%blocksize > 0 || len(input) < blocksize {
 		panic(fmt.Sprintf("Input size of %d not a multiple of %d or to small", len(input), blocksize))
 	}
 
-	// start afer the iv
-	for processed := blocksize; processed < len(input); processed += blocksize {
-		previous := input[processed-blocksize : processed]
+	plaintext := make([]byte, len(input))
+	previous := iv
 
-		c.Decrypt(plaintext[processed-blocksize:processed],
+	for processed := 0; processed < len(input); processed += blocksize {
+		c.Decrypt(plaintext[processed:processed+blocksize],
 			input[processed:processed+blocksize])
 
-		copy(plaintext[processed-blocksize:processed],
-			XOR(previous, plaintext[processed-blocksize:processed]))
+		copy(plaintext[processed:processed+blocksize],
+			XOR(previous, plaintext[processed:processed+blocksize]))
+
+		previous = input[processed : processed+blocksize]
 	}
 
 	pad_val := plaintext[len(plaintext)-1]
@@ -117,7 +147,8 @@ func CBCDecrypt(input, key []byte) ([]byte, error) {
 
 }
 
-func generateSecureRandomNumber(nonInclusiveUpperBound int) int {
+// GenerateSecureRandomNumber returns a uniform number in [0, nonInclusiveUpperBound)
+func GenerateSecureRandomNumber(nonInclusiveUpperBound int) int {
 
 	choice, err := rand.Int(rand.Reader, big.NewInt(int64(nonInclusiveUpperBound)))
 
@@ -129,15 +160,15 @@ func generateSecureRandomNumber(nonInclusiveUpperBound int) int {
 
 func ECBorCBC(plaintext []byte) (ciphertext []byte, isECB bool) {
 
-	isECB = generateSecureRandomNumber(2) == 0
+	isECB = GenerateSecureRandomNumber(2) == 0
 	key := make([]byte, 16)
 
 	if _, err := rand.Reader.Read(key); err != nil {
 		panic("Not enough randomness")
 	}
 
-	randomSuffix := make([]byte, generateSecureRandomNumber(5+1)+5)
-	randomPrefix := make([]byte, generateSecureRandomNumber(5+1)+5)
+	randomSuffix := make([]byte, GenerateSecureRandomNumber(5+1)+5)
+	randomPrefix := make([]byte, GenerateSecureRandomNumber(5+1)+5)
 
 	if _, err := rand.Reader.Read(randomSuffix); err != nil {
 		panic("Not enough randomness")
@@ -319,3 +319,55 @@ func TestPCKS7Unpad(t *testing.T) {
 		})
 	}
 }
+
+func TestMT19937(t *testing.T) {
+
+	// reference values of mt19937 with the default seed 5489
+	expected := []uint32{3499211612, 581869302, 3890346734, 3586334585, 545404204}
+	mt := set.NewMT19937(5489)
+
+	for i, want := range expected {
+		if got := mt.Uint32(); got != want {
+			t.Fatalf("Output %d: expected %d, but got %d", i, want, got)
+		}
+	}
+}
+
+func TestCommentEnvelope(t *testing.T) {
+
+	envelope := set.CommentEnvelope([]byte(";admin=true;"))
+
+	if set.IsAdminEnvelope(envelope) {
+		t.Fatalf("Injection was not quoted: %s", envelope)
+	}
+
+	if !set.IsAdminEnvelope([]byte("comment1=x;admin=true;comment2=y")) {
+		t.Fatal("Expected admin")
+	}
+}
+
+func TestCBCBitflipOracle(t *testing.T) {
+
+	encrypt, isAdmin := set.CBCBitflipOracleFactory()
+
+	if isAdmin(encrypt([]byte(";admin=true;"))) {
+		t.Fatal("Oracle accepted injected userdata")
+	}
+}
+
+func TestCBCWithIV(t *testing.T) {
+
+	key := []byte("YELLOW SUBMARINE")
+	iv := []byte("SUBMARINE YELLOW")
+	input := []byte("Some plaintext that spans multiple blocks")
+
+	ciphertext := set.CBCEncryptWithIV(input, key, iv)
+
+	if decrypted, err := set.CBCDecrypt(append(iv, ciphertext...), key); err != nil || !bytes.Equal(decrypted, input) {
+		t.Fatalf("Expected %s, but got %s (%v)", input, decrypted, err)
+	}
+
+	if decrypted, err := set.CBCDecryptWithIV(ciphertext, key, iv); err != nil || !bytes.Equal(decrypted, input) {
+		t.Fatalf("Expected %s, but got %s (%v)", input, decrypted, err)
+	}
+}
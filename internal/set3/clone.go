@@ -0,0 +1,62 @@
+package set
+
+import (
+	set2 "cryptopals/internal/set2"
+	"fmt"
+)
+
+const mtStateSize = 624
+
+func undoRightShiftXor(y uint32, shift uint) uint32 {
+	// every round recovers shift more of the upper bits
+	result := y
+	for i := uint(0); i < 32; i += shift {
+		result = y ^ (result >> shift)
+	}
+	return result
+}
+
+func undoLeftShiftXorAnd(y uint32, shift uint, mask uint32) uint32 {
+	// every round recovers shift more of the lower bits
+	result := y
+	for i := uint(0); i < 32; i += shift {
+		result = y ^ ((result << shift) & mask)
+	}
+	return result
+}
+
+func Untemper(y uint32) uint32 {
+	y = undoRightShiftXor(y, 18)
+	y = undoLeftShiftXorAnd(y, 15, 0xefc60000)
+	y = undoLeftShiftXorAnd(y, 7, 0x9d2c5680)
+	y = undoRightShiftXor(y, 11)
+	return y
+}
+
+// CloneMT19937 rebuilds a generator from at least 624 consecutive outputs.
+// The outputs do not need to start at a twist: the recurrence of MT19937 only
+// needs the last 624 elements of the sequence, so the untempered outputs can
+// be treated as a fresh state regardless of their offset. Outputs beyond the
+// first 624 are used to check that the sequence is consistent. The returned
+// generator continues right after the last given output.
+func CloneMT19937(outputs []uint32) (*set2.MT19937, error) {
+
+	if len(outputs) < mtStateSize {
+		return nil, fmt.Errorf("need at least %d outputs, got %d", mtStateSize, len(outputs))
+	}
+
+	var state [mtStateSize]uint32
+	for i := range state {
+		state[i] = Untemper(outputs[i])
+	}
+
+	clone := set2.NewMT19937FromState(state)
+
+	for i, output := range outputs[mtStateSize:] {
+		if predicted := clone.Uint32(); predicted != output {
+			return nil, fmt.Errorf("output %d is inconsistent: predicted %d, got %d", i+mtStateSize, predicted, output)
+		}
+	}
+
+	return clone, nil
+}
@@ -0,0 +1,52 @@
+package set
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"fmt"
+)
+
+// CTRKeystream returns length bytes of keystream beginning at byte offset.
+// The counter block is the 64 bit nonce followed by the 64 bit block counter,
+// both little endian, as described in challenge 18.
+func CTRKeystream(key []byte, nonce uint64, offset, length int) []byte {
+
+	c, err := aes.NewCipher(key)
+
+	if err != nil {
+		panic(fmt.Sprintf("Could not create AES-Cipher with key [%x] (%d)", key, len(key)))
+	}
+
+	if offset < 0 || length < 0 {
+		panic(fmt.Sprintf("Invalid keystream range offset %d length %d", offset, length))
+	}
+
+	blocksize := c.BlockSize()
+	counterBlock := make([]byte, blocksize)
+	keyBlock := make([]byte, blocksize)
+	binary.LittleEndian.PutUint64(counterBlock[:8], nonce)
+
+	keystream := make([]byte, 0, length+blocksize)
+
+	// start at the block containing the offset and cut the beginning off
+	skip := offset % blocksize
+	for counter := offset / blocksize; len(keystream) < length+skip; counter++ {
+		binary.LittleEndian.PutUint64(counterBlock[8:], uint64(counter))
+		c.Encrypt(keyBlock, counterBlock)
+		keystream = append(keystream, keyBlock...)
+	}
+
+	return keystream[skip : skip+length]
+}
+
+// CTR encrypts and decrypts, since both are the same operation
+func CTR(input, key []byte, nonce uint64) []byte {
+	output := make([]byte, len(input))
+	keystream := CTRKeystream(key, nonce, 0, len(input))
+
+	for i := range input {
+		output[i] = input[i] ^ keystream[i]
+	}
+
+	return output
+}
@@ -0,0 +1,116 @@
+package set
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	set2 "cryptopals/internal/set2"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+type mt19937Stream struct {
+	mt        *set2.MT19937
+	keystream [4]byte
+	// bytes of keystream already used, starts with an empty buffer
+	used int
+}
+
+func newMT19937Stream(seed uint32) *mt19937Stream {
+	return &mt19937Stream{
+		mt:   set2.NewMT19937(seed),
+		used: 4,
+	}
+}
+
+// NewMT19937Stream creates the challenge 24 stream cipher. Every output of
+// MT19937 provides four bytes of keystream in little endian order.
+func NewMT19937Stream(seed uint16) cipher.Stream {
+	return newMT19937Stream(uint32(seed))
+}
+
+func (s *mt19937Stream) XORKeyStream(dst, src []byte) {
+
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+
+	for i, b := range src {
+		if s.used == len(s.keystream) {
+			binary.LittleEndian.PutUint32(s.keystream[:], s.mt.Uint32())
+			s.used = 0
+		}
+		dst[i] = b ^ s.keystream[s.used]
+		s.used++
+	}
+}
+
+// MT19937StreamOracle encrypts a random prefix of 5 to 20 bytes followed by the
+// known plaintext under a random 16 bit seed.
+func MT19937StreamOracle(knownPlaintext []byte) (ciphertext []byte, seed uint16) {
+
+	seed = uint16(set2.GenerateSecureRandomNumber(1 << 16))
+	prefix := make([]byte, set2.GenerateSecureRandomNumber(20-5+1)+5)
+
+	if _, err := rand.Reader.Read(prefix); err != nil {
+		panic("Not enough randomness")
+	}
+
+	plaintext := append(prefix, knownPlaintext...)
+	ciphertext = make([]byte, len(plaintext))
+	NewMT19937Stream(seed).XORKeyStream(ciphertext, plaintext)
+
+	return ciphertext, seed
+}
+
+// RecoverMT19937StreamSeed brute forces the 16 bit seed by decrypting with
+// every possible seed until the plaintext ends with the known suffix.
+func RecoverMT19937StreamSeed(ciphertext, knownSuffix []byte) (uint16, error) {
+
+	if len(knownSuffix) == 0 || len(knownSuffix) > len(ciphertext) {
+		return 0, errors.New("known suffix must be non-empty and not longer than the ciphertext")
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+
+	for seed := 0; seed < 1<<16; seed++ {
+		NewMT19937Stream(uint16(seed)).XORKeyStream(plaintext, ciphertext)
+		if bytes.HasSuffix(plaintext, knownSuffix) {
+			return uint16(seed), nil
+		}
+	}
+
+	return 0, errors.New("no seed produces the known suffix")
+}
+
+func timeSeededToken(timestamp int64, length int) []byte {
+	token := make([]byte, length)
+	newMT19937Stream(uint32(timestamp)).XORKeyStream(token, token)
+	return token
+}
+
+// PasswordResetToken creates a 16 byte token from MT19937 seeded with the
+// current time.
+func PasswordResetToken(clock Clock) []byte {
+	return timeSeededToken(clock.Now().Unix(), 16)
+}
+
+// IsTimeSeededToken checks whether the token was generated from MT19937 seeded
+// with a timestamp of the last window.
+func IsTimeSeededToken(token []byte, clock Clock, window time.Duration) bool {
+
+	if len(token) == 0 {
+		return false
+	}
+
+	end := clock.Now().Unix()
+
+	for ts := end - int64(window/time.Second); ts <= end; ts++ {
+		if bytes.Equal(timeSeededToken(ts, len(token)), token) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,73 @@
+package set
+
+import (
+	set2 "cryptopals/internal/set2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimestampSeededOutput waits a random amount of seconds (40 to 1000),
+// seeds MT19937 with the current unix timestamp, waits again and returns the
+// first output together with the seed to check the attack.
+func TimestampSeededOutput(clock Clock) (output uint32, seed uint32) {
+
+	clock.Sleep(time.Duration(set2.GenerateSecureRandomNumber(1000-40+1)+40) * time.Second)
+
+	seed = uint32(clock.Now().Unix())
+	output = set2.NewMT19937(seed).Uint32()
+
+	clock.Sleep(time.Duration(set2.GenerateSecureRandomNumber(1000-40+1)+40) * time.Second)
+
+	return output, seed
+}
+
+// CrackTimestampSeed tries every timestamp in [now-window, now] as a seed and
+// returns all seeds whose first output equals output in ascending order.
+// The window is split among the given number of workers.
+func CrackTimestampSeed(output uint32, clock Clock, window time.Duration, workers int) []uint32 {
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	end := clock.Now().Unix()
+	start := end - int64(window/time.Second)
+	candidates := end - start + 1
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		seeds []uint32
+	)
+
+	chunk := (candidates + int64(workers) - 1) / int64(workers)
+
+	for from := start; from <= end; from += chunk {
+		to := from + chunk - 1
+		if to > end {
+			to = end
+		}
+
+		wg.Add(1)
+		go func(from, to int64) {
+			defer wg.Done()
+
+			mt := &set2.MT19937{}
+			for ts := from; ts <= to; ts++ {
+				mt.Seed(uint32(ts))
+				if mt.Uint32() == output {
+					mu.Lock()
+					seeds = append(seeds, uint32(ts))
+					mu.Unlock()
+				}
+			}
+		}(from, to)
+	}
+
+	wg.Wait()
+
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i] < seeds[j] })
+
+	return seeds
+}
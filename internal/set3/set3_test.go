@@ -0,0 +1,199 @@
+package set_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	set2 "cryptopals/internal/set2"
+	set "cryptopals/internal/set3"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCrackTimestampSeed(t *testing.T) {
+
+	clock := set.NewFakeClock(time.Unix(1600000000, 0))
+	output, seed := set.TimestampSeededOutput(clock)
+
+	// both waits are at most 1000 seconds, so 2000 seconds cover the seed
+	seeds := set.CrackTimestampSeed(output, clock, 2000*time.Second, 8)
+
+	found := false
+	for _, s := range seeds {
+		if s == seed {
+			found = true
+		}
+		if set2.NewMT19937(s).Uint32() != output {
+			t.Errorf("Seed %d does not produce output %d", s, output)
+		}
+	}
+
+	if !found {
+		t.Fatalf("Expected seed %d in %v", seed, seeds)
+	}
+}
+
+func TestCrackTimestampSeedOutsideWindow(t *testing.T) {
+
+	clock := set.NewFakeClock(time.Unix(1600000000, 0))
+	output, _ := set.TimestampSeededOutput(clock)
+
+	if seeds := set.CrackTimestampSeed(output, clock, 10*time.Second, 4); len(seeds) != 0 {
+		t.Fatalf("Expected no seeds, got %v", seeds)
+	}
+}
+
+func TestUntemper(t *testing.T) {
+
+	for _, y := range []uint32{0, 1, 0xffffffff, 0xdeadbeef, 0x80000000, 123456789} {
+		if got := set.Untemper(set2.Temper(y)); got != y {
+			t.Errorf("Expected %x, but got %x", y, got)
+		}
+	}
+}
+
+func TestCloneMT19937(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		skip    int
+		outputs int
+	}{
+		{name: "Aligned to twist", skip: 0, outputs: 624},
+		{name: "Not aligned to twist", skip: 100, outputs: 624},
+		{name: "More outputs than state", skip: 313, outputs: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := set2.NewMT19937(1337)
+
+			for i := 0; i < tt.skip; i++ {
+				mt.Uint32()
+			}
+
+			outputs := make([]uint32, tt.outputs)
+			for i := range outputs {
+				outputs[i] = mt.Uint32()
+			}
+
+			clone, err := set.CloneMT19937(outputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 2000; i++ {
+				if want, got := mt.Uint32(), clone.Uint32(); want != got {
+					t.Fatalf("Prediction %d: expected %d, but got %d", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCloneMT19937Errors(t *testing.T) {
+
+	mt := set2.NewMT19937(42)
+	outputs := make([]uint32, 700)
+	for i := range outputs {
+		outputs[i] = mt.Uint32()
+	}
+
+	if _, err := set.CloneMT19937(outputs[:623]); err == nil {
+		t.Error("Expected error for too few outputs")
+	}
+
+	outputs[650] ^= 1
+
+	if _, err := set.CloneMT19937(outputs); err == nil {
+		t.Error("Expected error for inconsistent outputs")
+	}
+}
+
+func TestMT19937Stream(t *testing.T) {
+
+	plaintext := []byte("YELLOW SUBMARINE, but encrypted with a twister")
+	ciphertext := make([]byte, len(plaintext))
+	set.NewMT19937Stream(1234).XORKeyStream(ciphertext, plaintext)
+
+	if bytes.Equal(ciphertext, plaintext) {
+		t.Fatal("Ciphertext equals plaintext")
+	}
+
+	// decrypt in uneven chunks to cross keystream word boundaries
+	decrypted := make([]byte, len(ciphertext))
+	stream := set.NewMT19937Stream(1234)
+	stream.XORKeyStream(decrypted[:3], ciphertext[:3])
+	stream.XORKeyStream(decrypted[3:10], ciphertext[3:10])
+	stream.XORKeyStream(decrypted[10:], ciphertext[10:])
+
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("Expected %s, but got %s", plaintext, decrypted)
+	}
+}
+
+func TestRecoverMT19937StreamSeed(t *testing.T) {
+
+	known := bytes.Repeat([]byte("A"), 14)
+	ciphertext, seed := set.MT19937StreamOracle(known)
+
+	recovered, err := set.RecoverMT19937StreamSeed(ciphertext, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recovered != seed {
+		t.Fatalf("Expected seed %d, but got %d", seed, recovered)
+	}
+}
+
+func TestIsTimeSeededToken(t *testing.T) {
+
+	clock := set.NewFakeClock(time.Unix(1600000000, 0))
+	token := set.PasswordResetToken(clock)
+	clock.Sleep(30 * time.Second)
+
+	if !set.IsTimeSeededToken(token, clock, time.Minute) {
+		t.Error("Expected token to be detected as time seeded")
+	}
+
+	if set.IsTimeSeededToken(token, clock, 10*time.Second) {
+		t.Error("Token should not be found outside of the window")
+	}
+
+	random := make([]byte, len(token))
+	if _, err := rand.Reader.Read(random); err != nil {
+		panic("Not enough randomness")
+	}
+
+	if set.IsTimeSeededToken(random, clock, time.Hour) {
+		t.Error("Random token detected as time seeded")
+	}
+}
+
+func TestCTR(t *testing.T) {
+
+	ciphertext, err := base64.StdEncoding.DecodeString("L77na/nrFsKvynd6HzOoG7GHTLXsTVu9qvY/2syLXzhPweyyMTJULu/6/kXX0KSvoOLSFQ==")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte("Yo, VIP Let's kick it Ice, Ice, baby Ice, Ice, baby ")
+	key := []byte("YELLOW SUBMARINE")
+
+	if observed := set.CTR(ciphertext, key, 0); !bytes.Equal(observed, expected) {
+		t.Fatalf("Expected %s, but got %s", expected, observed)
+	}
+}
+
+func TestCTRKeystreamOffset(t *testing.T) {
+
+	key := []byte("YELLOW SUBMARINE")
+	full := set.CTRKeystream(key, 7, 0, 100)
+
+	for _, offset := range []int{0, 1, 15, 16, 17, 50} {
+		if part := set.CTRKeystream(key, 7, offset, 30); !bytes.Equal(part, full[offset:offset+30]) {
+			t.Errorf("Keystream at offset %d does not match", offset)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package set
+
+import (
+	"crypto/rand"
+	set2 "cryptopals/internal/set2"
+	set3 "cryptopals/internal/set3"
+	"errors"
+)
+
+// CTRBitflipOracleFactory is the CTR equivalent of set2's
+// CBCBitflipOracleFactory and shares the same envelope.
+func CTRBitflipOracleFactory() (encrypt func([]byte) []byte, isAdmin func([]byte) bool) {
+
+	key := make([]byte, 16)
+
+	if _, err := rand.Reader.Read(key); err != nil {
+		panic("Not enough randomness")
+	}
+
+	encrypt = func(userdata []byte) []byte {
+		return set3.CTR(set2.CommentEnvelope(userdata), key, 0)
+	}
+
+	isAdmin = func(ciphertext []byte) bool {
+		return set2.IsAdminEnvelope(set3.CTR(ciphertext, key, 0))
+	}
+
+	return encrypt, isAdmin
+}
+
+func CTRBitflipAttack(encrypt func([]byte) []byte, isAdmin func([]byte) bool) ([]byte, error) {
+
+	// the first byte that differs between two encryptions is the start of the userdata
+	first, second := encrypt([]byte("A")), encrypt([]byte("B"))
+	offset := 0
+
+	for ; offset < len(first) && offset < len(second); offset++ {
+		if first[offset] != second[offset] {
+			break
+		}
+	}
+
+	if offset == len(first) || offset == len(second) {
+		return nil, errors.New("could not locate userdata in ciphertext")
+	}
+
+	/*
+		';' and '=' get quoted, so we send neighbours of them which differ in
+		the lowest bit and flip the bit in the ciphertext:
+
+			':' ^ 1 = ';'	'<' ^ 1 = '='
+	*/
+
+	target := []byte(";admin=true;")
+	payload := []byte(":admin<true:")
+
+	ciphertext := encrypt(payload)
+
+	for i := range payload {
+		ciphertext[offset+i] ^= payload[i] ^ target[i]
+	}
+
+	if !isAdmin(ciphertext) {
+		return nil, errors.New("injection was not accepted")
+	}
+
+	return ciphertext, nil
+}
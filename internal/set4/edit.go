@@ -0,0 +1,64 @@
+package set
+
+import (
+	"crypto/rand"
+	set3 "cryptopals/internal/set3"
+	"fmt"
+)
+
+// Edit re-encrypts newtext at offset in place by seeking into the keystream,
+// the ciphertext is expected to be encrypted under a zero nonce. Like append,
+// the ciphertext grows if newtext reaches past its end, so the result has to
+// be used.
+func Edit(ciphertext, key []byte, offset int, newtext []byte) []byte {
+
+	if offset < 0 || offset > len(ciphertext) {
+		panic(fmt.Sprintf("Offset %d outside of ciphertext with length %d", offset, len(ciphertext)))
+	}
+
+	if end := offset + len(newtext); end > len(ciphertext) {
+		ciphertext = append(ciphertext, make([]byte, end-len(ciphertext))...)
+	}
+
+	keystream := set3.CTRKeystream(key, 0, offset, len(newtext))
+
+	// newtext may alias the ciphertext at another offset, which the writes
+	// below would overwrite before it is read
+	newtext = append([]byte{}, newtext...)
+
+	for i, b := range newtext {
+		ciphertext[offset+i] = b ^ keystream[i]
+	}
+
+	return ciphertext
+}
+
+// CTREditOracleFactory encrypts the plaintext under a random key and exposes
+// the edit function without the key.
+func CTREditOracleFactory(plaintext []byte) (ciphertext []byte, edit func([]byte, int, []byte) []byte) {
+
+	key := make([]byte, 16)
+
+	if _, err := rand.Reader.Read(key); err != nil {
+		panic("Not enough randomness")
+	}
+
+	ciphertext = set3.CTR(plaintext, key, 0)
+
+	return ciphertext, func(ciphertext []byte, offset int, newtext []byte) []byte {
+		return Edit(ciphertext, key, offset, newtext)
+	}
+}
+
+func RecoverCTRPlaintextWithEdit(ciphertext []byte, edit func([]byte, int, []byte) []byte) []byte {
+
+	/*
+		edit encrypts the newtext with the same keystream:
+
+			edit(C, 0, C) = C ^ K = P ^ K ^ K = P
+
+		so writing the ciphertext over itself decrypts it
+	*/
+
+	return edit(ciphertext, 0, ciphertext)
+}
@@ -0,0 +1,115 @@
+package set
+
+import (
+	"crypto/subtle"
+	"hash"
+	"time"
+)
+
+// HMAC works with any hash, e.g. SHA1Family.New, MD4Family.New or sha256.New
+type HMAC struct {
+	inner, outer hash.Hash
+	ipad, opad   []byte
+}
+
+var _ hash.Hash = (*HMAC)(nil)
+
+func NewHMAC(h func() hash.Hash, key []byte) *HMAC {
+
+	mac := &HMAC{inner: h(), outer: h()}
+	blocksize := mac.inner.BlockSize()
+
+	// keys longer than a block are hashed first
+	if len(key) > blocksize {
+		mac.outer.Write(key)
+		key = mac.outer.Sum(nil)
+	}
+
+	mac.ipad = make([]byte, blocksize)
+	mac.opad = make([]byte, blocksize)
+	copy(mac.ipad, key)
+	copy(mac.opad, key)
+
+	for i := range mac.ipad {
+		mac.ipad[i] ^= 0x36
+		mac.opad[i] ^= 0x5c
+	}
+
+	mac.Reset()
+	return mac
+}
+
+func (mac *HMAC) Reset() {
+	mac.inner.Reset()
+	mac.inner.Write(mac.ipad)
+}
+
+func (mac *HMAC) Size() int      { return mac.outer.Size() }
+func (mac *HMAC) BlockSize() int { return mac.inner.BlockSize() }
+
+func (mac *HMAC) Write(p []byte) (int, error) {
+	return mac.inner.Write(p)
+}
+
+func (mac *HMAC) Sum(b []byte) []byte {
+	inner := mac.inner.Sum(nil)
+
+	mac.outer.Reset()
+	mac.outer.Write(mac.opad)
+	mac.outer.Write(inner)
+
+	return mac.outer.Sum(b)
+}
+
+func HMACSum(h func() hash.Hash, key, message []byte) []byte {
+	mac := NewHMAC(h, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+type CompareMode int
+
+const (
+	// ConstantTime compares with crypto/subtle
+	ConstantTime CompareMode = iota
+	// Insecure returns on the first mismatch and sleeps for every matching byte
+	Insecure
+)
+
+// insecureCompare leaks the length of the matching prefix by sleeping for
+// every matching byte
+func insecureCompare(a, b []byte, delay time.Duration) bool {
+
+	for i := range a {
+		if i >= len(b) || a[i] != b[i] {
+			return false
+		}
+		time.Sleep(delay)
+	}
+
+	return len(a) == len(b)
+}
+
+// HMACVerifier is the MAC check used by the oracle servers. Delay is the time
+// spent per matching byte in Insecure mode.
+type HMACVerifier struct {
+	Hash  func() hash.Hash
+	Key   []byte
+	Mode  CompareMode
+	Delay time.Duration
+}
+
+func (v *HMACVerifier) Sign(message []byte) []byte {
+	return HMACSum(v.Hash, v.Key, message)
+}
+
+func (v *HMACVerifier) Verify(message, mac []byte) bool {
+
+	expected := v.Sign(message)
+
+	if v.Mode == Insecure {
+		return insecureCompare(expected, mac, v.Delay)
+	}
+
+	return subtle.ConstantTimeCompare(expected, mac) == 1
+}
@@ -0,0 +1,90 @@
+package set
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	set2 "cryptopals/internal/set2"
+	"errors"
+	"fmt"
+)
+
+// InvalidASCIIError leaks the whole plaintext, just like a verbose error
+// message of a web application would.
+type InvalidASCIIError struct {
+	Plaintext []byte
+}
+
+func (e *InvalidASCIIError) Error() string {
+	return fmt.Sprintf("plaintext contains high-ASCII bytes: %x", e.Plaintext)
+}
+
+// KeyAsIVOracle encrypts with CBC and reuses the key as IV
+type KeyAsIVOracle struct {
+	key []byte
+}
+
+func NewKeyAsIVOracle() *KeyAsIVOracle {
+
+	key := make([]byte, 16)
+
+	if _, err := rand.Reader.Read(key); err != nil {
+		panic("Not enough randomness")
+	}
+
+	return &KeyAsIVOracle{key: key}
+}
+
+func (o *KeyAsIVOracle) Encrypt(plaintext []byte) []byte {
+	return set2.CBCEncryptWithIV(plaintext, o.key, o.key)
+}
+
+// Decrypt rejects plaintexts with bytes above 127 with an InvalidASCIIError.
+// The check happens before the padding is verified.
+func (o *KeyAsIVOracle) Decrypt(ciphertext []byte) error {
+
+	plaintext, err := set2.CBCDecryptWithIV(ciphertext, o.key, o.key)
+
+	for _, b := range plaintext {
+		if b > 127 {
+			return &InvalidASCIIError{Plaintext: plaintext}
+		}
+	}
+
+	return err
+}
+
+func (o *KeyAsIVOracle) IsKey(key []byte) bool {
+	return bytes.Equal(o.key, key)
+}
+
+func RecoverKeyAsIV(encrypt func([]byte) []byte, decrypt func([]byte) error) ([]byte, error) {
+
+	blocksize := aes.BlockSize
+	ciphertext := encrypt(bytes.Repeat([]byte("A"), 3*blocksize))
+
+	/*
+		C' = C1 || 0 || C1
+
+		P'1 = D(C1) ^ IV = D(C1) ^ K
+		P'3 = D(C1) ^ 0
+
+		=> K = P'1 ^ P'3
+	*/
+
+	first := ciphertext[:blocksize]
+	modified := append(append(append([]byte{}, first...), make([]byte, blocksize)...), first...)
+
+	var asciiErr *InvalidASCIIError
+	if err := decrypt(modified); !errors.As(err, &asciiErr) {
+		return nil, fmt.Errorf("oracle did not leak the plaintext: %v", err)
+	}
+
+	leaked := asciiErr.Plaintext
+
+	if len(leaked) < 3*blocksize {
+		return nil, errors.New("leaked plaintext too short")
+	}
+
+	return set2.XOR(leaked[:blocksize], leaked[2*blocksize:3*blocksize]), nil
+}
@@ -0,0 +1,116 @@
+package set
+
+import (
+	"crypto/rand"
+	set2 "cryptopals/internal/set2"
+	"fmt"
+	"hash"
+)
+
+const commentMessage = "comment1=cooking%20MCs;userdata=foo;comment2=%20like%20a%20pound%20of%20bacon"
+
+// MerkleDamgard is a hash family which can be resumed from a digest, this is
+// all a length extension attack needs.
+type MerkleDamgard interface {
+	New() hash.Hash
+	// Resume continues from a digest after processed bytes including padding
+	Resume(digest []byte, processed uint64) hash.Hash
+	Padding(messageLength uint64) []byte
+}
+
+type sha1Family struct{}
+
+func (sha1Family) New() hash.Hash { return NewSHA1() }
+func (sha1Family) Resume(digest []byte, processed uint64) hash.Hash {
+	return NewSHA1FromDigest(digest, processed)
+}
+func (sha1Family) Padding(messageLength uint64) []byte { return SHA1Padding(messageLength) }
+
+type md4Family struct{}
+
+func (md4Family) New() hash.Hash { return NewMD4() }
+func (md4Family) Resume(digest []byte, processed uint64) hash.Hash {
+	return NewMD4FromDigest(digest, processed)
+}
+func (md4Family) Padding(messageLength uint64) []byte { return MD4Padding(messageLength) }
+
+var (
+	SHA1Family MerkleDamgard = sha1Family{}
+	MD4Family  MerkleDamgard = md4Family{}
+)
+
+// LengthExtension is a forged message together with its valid MAC and the
+// key length which produced it.
+type LengthExtension struct {
+	Message   []byte
+	MAC       []byte
+	KeyLength int
+}
+
+func macOracleFactory(sign func([]byte, []byte) []byte, verify func([]byte, []byte, []byte) bool) ([]byte, []byte, func([]byte, []byte) bool) {
+
+	key := make([]byte, set2.GenerateSecureRandomNumber(32-8+1)+8)
+
+	if _, err := rand.Reader.Read(key); err != nil {
+		panic("Not enough randomness")
+	}
+
+	message := []byte(commentMessage)
+
+	return message, sign(key, message), func(message, mac []byte) bool {
+		return verify(key, message, mac)
+	}
+}
+
+// SHA1MACOracleFactory signs the challenge 29 message with a random key of 8
+// to 32 bytes and returns a verifier for arbitrary messages.
+func SHA1MACOracleFactory() (message, mac []byte, verify func([]byte, []byte) bool) {
+	return macOracleFactory(SecretPrefixMAC, VerifySecretPrefixMAC)
+}
+
+// MD4MACOracleFactory is SHA1MACOracleFactory with MD4
+func MD4MACOracleFactory() (message, mac []byte, verify func([]byte, []byte) bool) {
+	return macOracleFactory(MD4SecretPrefixMAC, VerifyMD4SecretPrefixMAC)
+}
+
+// ExtendMAC forges message || glue padding || extension for a given key
+// length by continuing the hash from the registers in mac.
+func ExtendMAC(family MerkleDamgard, message, mac, extension []byte, keyLength int) (forged, forgedMAC []byte) {
+
+	originalLength := uint64(keyLength + len(message))
+	glue := family.Padding(originalLength)
+
+	forged = append(append(append([]byte{}, message...), glue...), extension...)
+
+	h := family.Resume(mac, originalLength+uint64(len(glue)))
+	h.Write(extension)
+
+	return forged, h.Sum(nil)
+}
+
+// LengthExtensionAttack guesses the key length in [minKeyLength, maxKeyLength]
+// until the oracle accepts the forged MAC.
+func LengthExtensionAttack(family MerkleDamgard, message, mac, extension []byte, minKeyLength, maxKeyLength int, verify func([]byte, []byte) bool) (*LengthExtension, error) {
+
+	for keyLength := minKeyLength; keyLength <= maxKeyLength; keyLength++ {
+		forged, forgedMAC := ExtendMAC(family, message, mac, extension, keyLength)
+
+		if verify(forged, forgedMAC) {
+			return &LengthExtension{
+				Message:   forged,
+				MAC:       forgedMAC,
+				KeyLength: keyLength,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no key length in [%d, %d] produced a valid MAC", minKeyLength, maxKeyLength)
+}
+
+func ExtendSHA1MAC(message, mac, extension []byte, keyLength int) (forged, forgedMAC []byte) {
+	return ExtendMAC(SHA1Family, message, mac, extension, keyLength)
+}
+
+func SHA1LengthExtensionAttack(message, mac, extension []byte, minKeyLength, maxKeyLength int, verify func([]byte, []byte) bool) (*LengthExtension, error) {
+	return LengthExtensionAttack(SHA1Family, message, mac, extension, minKeyLength, maxKeyLength, verify)
+}
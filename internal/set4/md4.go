@@ -0,0 +1,164 @@
+package set
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/bits"
+)
+
+const (
+	MD4Size      = 16
+	MD4BlockSize = 64
+)
+
+var md4Init = [4]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476}
+
+// message word order and shifts of the three rounds (RFC 1320)
+var (
+	md4Round2Order = [16]int{0, 4, 8, 12, 1, 5, 9, 13, 2, 6, 10, 14, 3, 7, 11, 15}
+	md4Round3Order = [16]int{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15}
+	md4Shifts      = [3][4]int{{3, 7, 11, 19}, {3, 5, 9, 13}, {3, 9, 11, 15}}
+)
+
+// MD4 mirrors SHA1 but uses little endian words and length.
+type MD4 struct {
+	h      [4]uint32
+	block  [MD4BlockSize]byte
+	nblock int
+	length uint64
+
+	// state to return to on Reset
+	initH      [4]uint32
+	initLength uint64
+}
+
+var _ hash.Hash = (*MD4)(nil)
+
+func NewMD4() *MD4 {
+	return NewMD4FromState(md4Init, 0)
+}
+
+func NewMD4FromState(h [4]uint32, processed uint64) *MD4 {
+
+	if processed%MD4BlockSize != 0 {
+		panic(fmt.Sprintf("Processed length %d not a multiple of %d", processed, MD4BlockSize))
+	}
+
+	m := &MD4{initH: h, initLength: processed}
+	m.Reset()
+	return m
+}
+
+func NewMD4FromDigest(digest []byte, processed uint64) *MD4 {
+
+	if len(digest) != MD4Size {
+		panic(fmt.Sprintf("Digest size of %d is not %d", len(digest), MD4Size))
+	}
+
+	var h [4]uint32
+	for i := range h {
+		h[i] = binary.LittleEndian.Uint32(digest[4*i:])
+	}
+
+	return NewMD4FromState(h, processed)
+}
+
+func (m *MD4) Reset() {
+	m.h = m.initH
+	m.length = m.initLength
+	m.nblock = 0
+}
+
+func (m *MD4) Size() int      { return MD4Size }
+func (m *MD4) BlockSize() int { return MD4BlockSize }
+
+func (m *MD4) State() [4]uint32 { return m.h }
+
+func (m *MD4) Write(p []byte) (int, error) {
+
+	n := len(p)
+	m.length += uint64(n)
+
+	for len(p) > 0 {
+		copied := copy(m.block[m.nblock:], p)
+		m.nblock += copied
+		p = p[copied:]
+
+		if m.nblock == MD4BlockSize {
+			m.compress(m.block[:])
+			m.nblock = 0
+		}
+	}
+
+	return n, nil
+}
+
+// Sum appends the digest to b without changing the state of m
+func (m *MD4) Sum(b []byte) []byte {
+
+	// work on a copy such that the caller can keep writing
+	c := *m
+	c.Write(MD4Padding(m.length))
+
+	digest := make([]byte, MD4Size)
+	for i, v := range c.h {
+		binary.LittleEndian.PutUint32(digest[4*i:], v)
+	}
+
+	return append(b, digest...)
+}
+
+func (m *MD4) compress(block []byte) {
+
+	var x [16]uint32
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(block[4*i:])
+	}
+
+	// r[0] is the register updated in the current step, the others follow as d, c, b
+	r := m.h
+
+	step := func(f uint32, k, s int, constant uint32) {
+		r[0] = bits.RotateLeft32(r[0]+f+x[k]+constant, s)
+		r[0], r[1], r[2], r[3] = r[3], r[0], r[1], r[2]
+	}
+
+	for i := 0; i < 16; i++ {
+		b, c, d := r[1], r[2], r[3]
+		step((b&c)|(^b&d), i, md4Shifts[0][i%4], 0)
+	}
+
+	for i := 0; i < 16; i++ {
+		b, c, d := r[1], r[2], r[3]
+		step((b&c)|(b&d)|(c&d), md4Round2Order[i], md4Shifts[1][i%4], 0x5a827999)
+	}
+
+	for i := 0; i < 16; i++ {
+		b, c, d := r[1], r[2], r[3]
+		step(b^c^d, md4Round3Order[i], md4Shifts[2][i%4], 0x6ed9eba1)
+	}
+
+	for i := range m.h {
+		m.h[i] += r[i]
+	}
+}
+
+func MD4Padding(messageLength uint64) []byte {
+	return MDPadding(messageLength, binary.LittleEndian)
+}
+
+func MD4Sum(message []byte) []byte {
+	m := NewMD4()
+	m.Write(message)
+	return m.Sum(nil)
+}
+
+func MD4SecretPrefixMAC(key, message []byte) []byte {
+	return MD4Sum(append(append([]byte{}, key...), message...))
+}
+
+func VerifyMD4SecretPrefixMAC(key, message, mac []byte) bool {
+	return subtle.ConstantTimeCompare(MD4SecretPrefixMAC(key, message), mac) == 1
+}
@@ -0,0 +1,445 @@
+package set_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	set1 "cryptopals/internal/set1"
+	set "cryptopals/internal/set4"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEdit(t *testing.T) {
+
+	key := []byte("YELLOW SUBMARINE")
+	ciphertext := set.Edit(nil, key, 0, []byte("Hello World, this is CTR"))
+	original := append([]byte{}, ciphertext...)
+
+	edited := set.Edit(ciphertext, key, 6, []byte("Gophr"))
+	expected := []byte("Hello Gophr, this is CTR")
+
+	if &edited[0] != &ciphertext[0] || bytes.Equal(ciphertext, original) {
+		t.Fatal("Edit did not re-encrypt in place")
+	}
+
+	extended := set.Edit(edited, key, len(edited), []byte("!"))
+
+	if len(extended) != len(edited)+1 || !bytes.Equal(extended[:len(edited)], edited) {
+		t.Fatal("Appending did not keep the ciphertext intact")
+	}
+
+	// encrypting the ciphertext again decrypts it
+	if decrypted := set.Edit(extended, key, 0, extended); !bytes.Equal(decrypted, append(expected, '!')) {
+		t.Fatalf("Expected %s!, but got %s", expected, decrypted)
+	}
+
+	// newtext overlapping the edited range at another offset
+	overlapping := set.Edit(nil, key, 0, []byte("0123456789abcdefghij"))
+	copied := append([]byte{}, overlapping...)
+
+	overlapping = set.Edit(overlapping, key, 5, overlapping[:10])
+	copied = set.Edit(copied, key, 5, append([]byte{}, copied[:10]...))
+
+	if !bytes.Equal(overlapping, copied) {
+		t.Fatalf("Expected %x, got %x", copied, overlapping)
+	}
+}
+
+func TestCTREditAttack(t *testing.T) {
+
+	f, err := os.Open("../set1/testdata/set1-ch7.txt")
+
+	if err != nil {
+		panic("Could not open file")
+	}
+
+	defer f.Close()
+
+	reader := bufio.NewScanner(f)
+	reader.Split(bufio.ScanLines)
+
+	var sb strings.Builder
+
+	for reader.Scan() {
+		sb.WriteString(reader.Text())
+	}
+
+	text, _ := base64.StdEncoding.DecodeString(sb.String())
+	plaintext := set1.DecryptAESECB(text, []byte("YELLOW SUBMARINE"))
+
+	ciphertext, edit := set.CTREditOracleFactory(plaintext)
+	recovered := set.RecoverCTRPlaintextWithEdit(ciphertext, edit)
+
+	if !bytes.Equal(recovered, plaintext) {
+		t.Fatal("Did not recover the plaintext")
+	}
+
+	t.Log(string(recovered))
+}
+
+func TestCTRBitflipAttack(t *testing.T) {
+
+	encrypt, isAdmin := set.CTRBitflipOracleFactory()
+
+	if isAdmin(encrypt([]byte(";admin=true;"))) {
+		t.Fatal("Oracle accepted injected userdata")
+	}
+
+	ciphertext, err := set.CTRBitflipAttack(encrypt, isAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isAdmin(ciphertext) {
+		t.Fatal("Forged ciphertext is not admin")
+	}
+}
+
+func TestRecoverKeyAsIV(t *testing.T) {
+
+	oracle := set.NewKeyAsIVOracle()
+
+	if err := oracle.Decrypt(oracle.Encrypt([]byte("just some ascii text"))); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := set.RecoverKeyAsIV(oracle.Encrypt, oracle.Decrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !oracle.IsKey(key) {
+		t.Fatalf("Recovered wrong key %x", key)
+	}
+}
+
+func TestSHA1(t *testing.T) {
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "", want: "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{input: "abc", want: "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{input: "abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq", want: "84983e441c3bd26ebaae4aa1f95129e5e54670f1"},
+		{input: strings.Repeat("a", 1000000), want: "34aa973cd4c4daa4f61eeb2bdbad27316534016f"},
+	}
+
+	for _, tt := range tests {
+		if got := hex.EncodeToString(set.SHA1Sum([]byte(tt.input))); got != tt.want {
+			t.Errorf("SHA1 of %d bytes = %s, want %s", len(tt.input), got, tt.want)
+		}
+	}
+
+	// compare against crypto/sha1 for every length around the padding boundaries
+	input := bytes.Repeat([]byte("YELLOW SUBMARINE"), 20)
+	for i := 0; i < len(input); i++ {
+		want := sha1.Sum(input[:i])
+		if got := set.SHA1Sum(input[:i]); !bytes.Equal(got, want[:]) {
+			t.Fatalf("Mismatch for length %d", i)
+		}
+	}
+}
+
+func TestSHA1Resume(t *testing.T) {
+
+	message := bytes.Repeat([]byte("A"), 2*set.SHA1BlockSize)
+
+	full := set.NewSHA1()
+	full.Write(message)
+	full.Write([]byte("tail"))
+
+	first := set.NewSHA1()
+	first.Write(message)
+
+	resumed := set.NewSHA1FromState(first.State(), uint64(len(message)))
+	resumed.Write([]byte("tail"))
+
+	if !bytes.Equal(full.Sum(nil), resumed.Sum(nil)) {
+		t.Fatal("Resumed hash differs")
+	}
+
+	// Sum must not change the state and Reset returns to the resumed state
+	resumed.Reset()
+	resumed.Write([]byte("tail"))
+
+	if !bytes.Equal(full.Sum(nil), resumed.Sum(nil)) {
+		t.Fatal("Reset did not restore the resumed state")
+	}
+}
+
+func TestSecretPrefixMAC(t *testing.T) {
+
+	key := []byte("YELLOW SUBMARINE")
+	message := []byte("comment1=cooking%20MCs;userdata=foo")
+	mac := set.SecretPrefixMAC(key, message)
+
+	if !set.VerifySecretPrefixMAC(key, message, mac) {
+		t.Fatal("Valid MAC rejected")
+	}
+
+	if set.VerifySecretPrefixMAC(key, append(message, '!'), mac) {
+		t.Fatal("MAC of tampered message accepted")
+	}
+
+	if set.VerifySecretPrefixMAC([]byte("ANOTHER KEY"), message, mac) {
+		t.Fatal("MAC with different key accepted")
+	}
+}
+
+func TestSHA1LengthExtensionAttack(t *testing.T) {
+
+	message, mac, verify := set.SHA1MACOracleFactory()
+
+	if !verify(message, mac) {
+		t.Fatal("Oracle rejected its own MAC")
+	}
+
+	result, err := set.SHA1LengthExtensionAttack(message, mac, []byte(";admin=true"), 0, 64, verify)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(result.Message, message) || !bytes.HasSuffix(result.Message, []byte(";admin=true")) {
+		t.Fatalf("Unexpected forged message %q", result.Message)
+	}
+
+	if !verify(result.Message, result.MAC) {
+		t.Fatal("Forged MAC rejected")
+	}
+
+	t.Logf("Key length %d, forged %q", result.KeyLength, result.Message)
+}
+
+func TestMD4(t *testing.T) {
+
+	// test suite of RFC 1320
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "", want: "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{input: "a", want: "bde52cb31de33e46245e05fbdbd6fb24"},
+		{input: "abc", want: "a448017aaf21d8525fc10ae87aa6729d"},
+		{input: "message digest", want: "d9130a8164549fe818874806e1c7014b"},
+		{input: "abcdefghijklmnopqrstuvwxyz", want: "d79e1c308aa5bbcdeea8ed63df412da9"},
+		{input: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789", want: "043f8582f241db351ce627e153e7f0e4"},
+		{input: strings.Repeat("1234567890", 8), want: "e33b4ddc9c38f2199c3e7b164fcc0536"},
+	}
+
+	for _, tt := range tests {
+		if got := hex.EncodeToString(set.MD4Sum([]byte(tt.input))); got != tt.want {
+			t.Errorf("MD4(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMD4Resume(t *testing.T) {
+
+	message := bytes.Repeat([]byte("A"), set.MD4BlockSize)
+
+	full := set.NewMD4()
+	full.Write(message)
+	full.Write([]byte("tail"))
+
+	first := set.NewMD4()
+	first.Write(message)
+
+	resumed := set.NewMD4FromState(first.State(), uint64(len(message)))
+	resumed.Write([]byte("tail"))
+
+	if !bytes.Equal(full.Sum(nil), resumed.Sum(nil)) {
+		t.Fatal("Resumed hash differs")
+	}
+}
+
+func TestMD4LengthExtensionAttack(t *testing.T) {
+
+	message, mac, verify := set.MD4MACOracleFactory()
+
+	result, err := set.LengthExtensionAttack(set.MD4Family, message, mac, []byte(";admin=true"), 0, 64, verify)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasSuffix(result.Message, []byte(";admin=true")) || !verify(result.Message, result.MAC) {
+		t.Fatalf("Forgery %q rejected", result.Message)
+	}
+
+	t.Logf("Key length %d, forged %q", result.KeyLength, result.Message)
+}
+
+func TestTrimmedMean(t *testing.T) {
+
+	samples := []time.Duration{9, 1, 5, 3, 100, 4, 2, 6, 8, 7}
+
+	if got := set.Median(samples); got != 5 {
+		t.Errorf("Median = %d, want 5", got)
+	}
+
+	// drops 1, 2 and 9, 100
+	if got := set.TrimmedMean(samples, 0.2); got != 5 {
+		t.Errorf("TrimmedMean = %d, want 5", got)
+	}
+
+	if got := set.TrimmedMean(samples, 0); got != 14 {
+		t.Errorf("Mean = %d, want 14", got)
+	}
+}
+
+func TestTimingLeakHandler(t *testing.T) {
+
+	server := httptest.NewServer(set.CreateTimingLeakHandler([]byte("YELLOW SUBMARINE"), 0))
+	defer server.Close()
+	client := server.Client()
+
+	// HMAC-SHA1 of "foo" under the key, see crypto/hmac
+	mac := hmac.New(sha1.New, []byte("YELLOW SUBMARINE"))
+	mac.Write([]byte("foo"))
+
+	resp, err := client.Get(server.URL + "/test?file=foo&signature=" + hex.EncodeToString(mac.Sum(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Expected OK, got", resp.StatusCode)
+	}
+
+	resp, err = client.Get(server.URL + "/test?file=bar&signature=" + hex.EncodeToString(mac.Sum(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatal("Expected internal server error, got", resp.StatusCode)
+	}
+}
+
+func TestTimingAttack(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("Timing attack takes several seconds")
+	}
+
+	key := make([]byte, 16)
+	if _, err := rand.Reader.Read(key); err != nil {
+		panic("Not enough randomness")
+	}
+
+	// the 5ms variant of challenge 32
+	server := httptest.NewServer(set.CreateTimingLeakHandler(key, 5*time.Millisecond))
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport.(*http.Transport).MaxIdleConnsPerHost = 64
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write([]byte("foo"))
+	expected := mac.Sum(nil)
+
+	start := time.Now()
+	recovered, err := set.TimingAttack(client, server.URL, "foo", sha1.Size, set.TimingAttackOptions{
+		Samples:       3,
+		MaxSamples:    24,
+		Concurrency:   64,
+		MaxBacktracks: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(recovered, expected) {
+		t.Fatalf("Expected %x, but got %x", expected, recovered)
+	}
+
+	t.Logf("Recovered %x in %s", recovered, time.Since(start))
+}
+
+func TestHMAC(t *testing.T) {
+
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			panic(err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name string
+		hash func() hash.Hash
+		key  []byte
+		data []byte
+		want string
+	}{
+		// RFC 2202
+		{"SHA1 case 1", set.SHA1Family.New, bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), "b617318655057264e28bc0b6fb378c8ef146be00"},
+		{"SHA1 case 2", set.SHA1Family.New, []byte("Jefe"), []byte("what do ya want for nothing?"), "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79"},
+		{"SHA1 case 3", set.SHA1Family.New, bytes.Repeat([]byte{0xaa}, 20), bytes.Repeat([]byte{0xdd}, 50), "125d7342b9ac11cd91a39af48aa17b4f63f175d3"},
+		{"SHA1 case 4", set.SHA1Family.New, decode("0102030405060708090a0b0c0d0e0f10111213141516171819"), bytes.Repeat([]byte{0xcd}, 50), "4c9007f4026250c6bc8414f9bf50c86c2d7235da"},
+		{"SHA1 case 5", set.SHA1Family.New, bytes.Repeat([]byte{0x0c}, 20), []byte("Test With Truncation"), "4c1a03424b55e07fe7f27be1d58bb9324a9a5a04"},
+		{"SHA1 case 6", set.SHA1Family.New, bytes.Repeat([]byte{0xaa}, 80), []byte("Test Using Larger Than Block-Size Key - Hash Key First"), "aa4ae5e15272d00e95705637ce8a3b55ed402112"},
+		{"SHA1 case 7", set.SHA1Family.New, bytes.Repeat([]byte{0xaa}, 80), []byte("Test Using Larger Than Block-Size Key and Larger Than One Block-Size Data"), "e8e99d0f45237d786d6bbaa7965c7808bbff1a91"},
+		// RFC 4231
+		{"SHA256 case 1", sha256.New, bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), "b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7"},
+		{"SHA256 case 2", sha256.New, []byte("Jefe"), []byte("what do ya want for nothing?"), "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{"SHA256 case 3", sha256.New, bytes.Repeat([]byte{0xaa}, 20), bytes.Repeat([]byte{0xdd}, 50), "773ea91e36800e46854db8ebd09181a72959098b3ef8c122d9635514ced565fe"},
+		{"SHA256 case 4", sha256.New, decode("0102030405060708090a0b0c0d0e0f10111213141516171819"), bytes.Repeat([]byte{0xcd}, 50), "82558a389a443c0ea4cc819899f2083a85f0faa3e578f8077a2e3ff46729665b"},
+		{"SHA256 case 6", sha256.New, bytes.Repeat([]byte{0xaa}, 131), []byte("Test Using Larger Than Block-Size Key - Hash Key First"), "60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54"},
+		{"SHA256 case 7", sha256.New, bytes.Repeat([]byte{0xaa}, 131), []byte("This is a test using a larger than block-size key and a larger than block-size data. The key needs to be hashed before being used by the HMAC algorithm."), "9b09ffa71b942fcb27635fbcd5b0e944bfdc63644f0713938a7f51535c3a35e2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(set.HMACSum(tt.hash, tt.key, tt.data)); got != tt.want {
+				t.Errorf("HMACSum() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHMACMD4(t *testing.T) {
+
+	// there are no official vectors, so check that it is a usable hash.Hash
+	mac := set.NewHMAC(set.MD4Family.New, []byte("key"))
+	mac.Write([]byte("The quick brown fox"))
+	first := mac.Sum(nil)
+
+	mac.Reset()
+	mac.Write([]byte("The quick "))
+	mac.Write([]byte("brown fox"))
+
+	if !bytes.Equal(first, mac.Sum(nil)) || len(first) != set.MD4Size {
+		t.Fatal("HMAC-MD4 is not consistent")
+	}
+}
+
+func TestHMACVerifier(t *testing.T) {
+
+	for _, mode := range []set.CompareMode{set.ConstantTime, set.Insecure} {
+		verifier := &set.HMACVerifier{Hash: sha256.New, Key: []byte("YELLOW SUBMARINE"), Mode: mode}
+		mac := verifier.Sign([]byte("foo"))
+
+		if !verifier.Verify([]byte("foo"), mac) {
+			t.Errorf("Mode %d rejected a valid MAC", mode)
+		}
+
+		if verifier.Verify([]byte("foo"), mac[:len(mac)-1]) || verifier.Verify([]byte("bar"), mac) {
+			t.Errorf("Mode %d accepted an invalid MAC", mode)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package set
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/bits"
+)
+
+const (
+	SHA1Size      = 20
+	SHA1BlockSize = 64
+)
+
+var sha1Init = [5]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476, 0xc3d2e1f0}
+
+// SHA1 is a plain implementation of SHA-1 whose registers and processed length
+// can be chosen, which crypto/sha1 does not allow.
+type SHA1 struct {
+	h      [5]uint32
+	block  [SHA1BlockSize]byte
+	nblock int
+	length uint64
+
+	// state to return to on Reset
+	initH      [5]uint32
+	initLength uint64
+}
+
+var _ hash.Hash = (*SHA1)(nil)
+
+func NewSHA1() *SHA1 {
+	return NewSHA1FromState(sha1Init, 0)
+}
+
+// NewSHA1FromState continues a hash after processed bytes, which must be a
+// multiple of the block size since SHA-1 only keeps whole blocks in its state.
+func NewSHA1FromState(h [5]uint32, processed uint64) *SHA1 {
+
+	if processed%SHA1BlockSize != 0 {
+		panic(fmt.Sprintf("Processed length %d not a multiple of %d", processed, SHA1BlockSize))
+	}
+
+	s := &SHA1{initH: h, initLength: processed}
+	s.Reset()
+	return s
+}
+
+// NewSHA1FromDigest loads the registers from a digest, this is the state after
+// the padding of the original message has been processed.
+func NewSHA1FromDigest(digest []byte, processed uint64) *SHA1 {
+
+	if len(digest) != SHA1Size {
+		panic(fmt.Sprintf("Digest size of %d is not %d", len(digest), SHA1Size))
+	}
+
+	var h [5]uint32
+	for i := range h {
+		h[i] = binary.BigEndian.Uint32(digest[4*i:])
+	}
+
+	return NewSHA1FromState(h, processed)
+}
+
+func (s *SHA1) Reset() {
+	s.h = s.initH
+	s.length = s.initLength
+	s.nblock = 0
+}
+
+func (s *SHA1) Size() int      { return SHA1Size }
+func (s *SHA1) BlockSize() int { return SHA1BlockSize }
+
+func (s *SHA1) State() [5]uint32 { return s.h }
+
+func (s *SHA1) Write(p []byte) (int, error) {
+
+	n := len(p)
+	s.length += uint64(n)
+
+	for len(p) > 0 {
+		copied := copy(s.block[s.nblock:], p)
+		s.nblock += copied
+		p = p[copied:]
+
+		if s.nblock == SHA1BlockSize {
+			s.compress(s.block[:])
+			s.nblock = 0
+		}
+	}
+
+	return n, nil
+}
+
+// Sum appends the digest to b without changing the state of s
+func (s *SHA1) Sum(b []byte) []byte {
+
+	// work on a copy such that the caller can keep writing
+	c := *s
+	c.Write(SHA1Padding(s.length))
+
+	digest := make([]byte, SHA1Size)
+	for i, v := range c.h {
+		binary.BigEndian.PutUint32(digest[4*i:], v)
+	}
+
+	return append(b, digest...)
+}
+
+func (s *SHA1) compress(block []byte) {
+
+	var w [80]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(block[4*i:])
+	}
+	for i := 16; i < 80; i++ {
+		w[i] = bits.RotateLeft32(w[i-3]^w[i-8]^w[i-14]^w[i-16], 1)
+	}
+
+	a, b, c, d, e := s.h[0], s.h[1], s.h[2], s.h[3], s.h[4]
+
+	for i := 0; i < 80; i++ {
+		var f, k uint32
+		switch {
+		case i < 20:
+			f = (b & c) | (^b & d)
+			k = 0x5a827999
+		case i < 40:
+			f = b ^ c ^ d
+			k = 0x6ed9eba1
+		case i < 60:
+			f = (b & c) | (b & d) | (c & d)
+			k = 0x8f1bbcdc
+		default:
+			f = b ^ c ^ d
+			k = 0xca62c1d6
+		}
+
+		temp := bits.RotateLeft32(a, 5) + f + e + k + w[i]
+		e = d
+		d = c
+		c = bits.RotateLeft32(b, 30)
+		b = a
+		a = temp
+	}
+
+	s.h[0] += a
+	s.h[1] += b
+	s.h[2] += c
+	s.h[3] += d
+	s.h[4] += e
+}
+
+// MDPadding is the Merkle-Damgård padding for a message of messageLength
+// bytes: 0x80, zeros up to 56 mod 64 and the length in bits in the given order.
+func MDPadding(messageLength uint64, order binary.ByteOrder) []byte {
+
+	zeros := (55 - messageLength%64 + 64) % 64
+	padding := make([]byte, 1+zeros+8)
+	padding[0] = 0x80
+	order.PutUint64(padding[1+zeros:], messageLength*8)
+
+	return padding
+}
+
+func SHA1Padding(messageLength uint64) []byte {
+	return MDPadding(messageLength, binary.BigEndian)
+}
+
+func SHA1Sum(message []byte) []byte {
+	s := NewSHA1()
+	s.Write(message)
+	return s.Sum(nil)
+}
+
+func SecretPrefixMAC(key, message []byte) []byte {
+	return SHA1Sum(append(append([]byte{}, key...), message...))
+}
+
+func VerifySecretPrefixMAC(key, message, mac []byte) bool {
+	return subtle.ConstantTimeCompare(SecretPrefixMAC(key, message), mac) == 1
+}
@@ -0,0 +1,289 @@
+package set
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+type TimingLeakHandler struct {
+	verifier *HMACVerifier
+}
+
+func (tl *TimingLeakHandler) test(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	signature, err := hex.DecodeString(values.Get("signature"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if tl.verifier.Verify([]byte(values.Get("file")), signature) {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// CreateTimingLeakHandler serves /test?file=foo&signature=46b4ec586117154dacd49d664e5d63fdc88efb51
+// and compares the HMAC byte by byte with the given delay per matching byte.
+func CreateTimingLeakHandler(key []byte, delay time.Duration) *http.ServeMux {
+
+	handler := TimingLeakHandler{
+		verifier: &HMACVerifier{
+			Hash:  SHA1Family.New,
+			Key:   key,
+			Mode:  Insecure,
+			Delay: delay,
+		},
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/test", handler.test)
+
+	return mux
+}
+
+func Median(samples []time.Duration) time.Duration {
+	return TrimmedMean(samples, 0.5)
+}
+
+// TrimmedMean drops the given fraction of samples on each end before averaging,
+// a fraction of 0.5 or more yields the median.
+func TrimmedMean(samples []time.Duration, fraction float64) time.Duration {
+
+	if len(samples) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	if fraction >= 0.5 {
+		mid := len(sorted) / 2
+		if len(sorted)%2 == 0 {
+			return (sorted[mid-1] + sorted[mid]) / 2
+		}
+		return sorted[mid]
+	}
+
+	cut := int(float64(len(sorted)) * fraction)
+	kept := sorted[cut : len(sorted)-cut]
+
+	var sum time.Duration
+	for _, s := range kept {
+		sum += s
+	}
+
+	return sum / time.Duration(len(kept))
+}
+
+type TimingAttackOptions struct {
+	// samples per candidate byte in the first round
+	Samples int
+	// the samples are doubled until a candidate stands out or this limit is hit
+	MaxSamples int
+	// concurrent requests
+	Concurrency int
+	// how often a position may be discarded to redo the previous one
+	MaxBacktracks int
+	// reduces the samples of one candidate, TrimmedMean with 0.2 by default
+	Statistic func([]time.Duration) time.Duration
+}
+
+func (opts *TimingAttackOptions) withDefaults() TimingAttackOptions {
+
+	o := *opts
+
+	if o.Samples < 1 {
+		o.Samples = 3
+	}
+	if o.MaxSamples < o.Samples {
+		o.MaxSamples = 16 * o.Samples
+	}
+	if o.Concurrency < 1 {
+		o.Concurrency = 64
+	}
+	if o.MaxBacktracks < 0 {
+		o.MaxBacktracks = 0
+	}
+	if o.Statistic == nil {
+		o.Statistic = func(samples []time.Duration) time.Duration { return TrimmedMean(samples, 0.2) }
+	}
+
+	return o
+}
+
+type timingClient struct {
+	client  *http.Client
+	baseURL string
+	file    string
+}
+
+func (tc *timingClient) request(signature []byte) (time.Duration, int, error) {
+
+	query := url.Values{}
+	query.Set("file", tc.file)
+	query.Set("signature", hex.EncodeToString(signature))
+
+	start := time.Now()
+	resp, err := tc.client.Get(tc.baseURL + "/test?" + query.Encode())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return time.Since(start), resp.StatusCode, nil
+}
+
+// measure samples every candidate for the byte at position and returns the
+// statistic of each candidate
+func (tc *timingClient) measure(known []byte, position, macSize, samples, concurrency int, statistic func([]time.Duration) time.Duration) ([256]time.Duration, error) {
+
+	type job struct {
+		candidate int
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		durations [256][]time.Duration
+		firstErr  error
+	)
+
+	jobs := make(chan job)
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			signature := make([]byte, macSize)
+			copy(signature, known[:position])
+
+			for j := range jobs {
+				signature[position] = byte(j.candidate)
+				d, _, err := tc.request(signature)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				durations[j.candidate] = append(durations[j.candidate], d)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// interleave the candidates such that drift affects all of them alike
+	for s := 0; s < samples; s++ {
+		for c := 0; c < 256; c++ {
+			jobs <- job{candidate: c}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	var stats [256]time.Duration
+	for c := range stats {
+		stats[c] = statistic(durations[c])
+	}
+
+	return stats, firstErr
+}
+
+// TimingAttack recovers the HMAC of file byte by byte. For every position all
+// candidates are sampled, a candidate is accepted once its gap to the runner-up
+// is at least half of its gap to the median of all candidates and that gap is
+// at least half of the leak observed for the first byte. Otherwise the samples
+// are doubled and after MaxSamples the previous byte is redone, as a wrong
+// earlier byte makes all candidates look alike.
+func TimingAttack(client *http.Client, baseURL, file string, macSize int, opts TimingAttackOptions) ([]byte, error) {
+
+	o := opts.withDefaults()
+	tc := &timingClient{client: client, baseURL: baseURL, file: file}
+	known := make([]byte, macSize)
+	backtracks := 0
+	// time leaked by one matching byte, estimated on the first position
+	var leak time.Duration
+
+	for position := 0; position < macSize-1; {
+
+		accepted := false
+
+		for samples := o.Samples; samples <= o.MaxSamples; samples *= 2 {
+			stats, err := tc.measure(known, position, macSize, samples, o.Concurrency, o.Statistic)
+			if err != nil {
+				return nil, err
+			}
+
+			sorted := append([]time.Duration{}, stats[:]...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+
+			best, runnerUp, median := sorted[0], sorted[1], sorted[len(sorted)/2]
+
+			gap := best - median
+
+			if gap > 0 && best-runnerUp >= gap/2 && gap >= leak/2 {
+				if leak == 0 {
+					leak = gap
+				}
+				for c, s := range stats {
+					if s == best {
+						known[position] = byte(c)
+						break
+					}
+				}
+				accepted = true
+				break
+			}
+		}
+
+		if accepted {
+			position++
+			continue
+		}
+
+		if position == 0 || backtracks >= o.MaxBacktracks {
+			return nil, fmt.Errorf("no candidate stands out at position %d", position)
+		}
+
+		backtracks++
+		position--
+	}
+
+	// the last byte does not need timing since the status code tells
+	signature := append([]byte{}, known...)
+
+	for c := 0; c < 256; c++ {
+		signature[macSize-1] = byte(c)
+
+		_, status, err := tc.request(signature)
+		if err != nil {
+			return nil, err
+		}
+
+		if status == http.StatusOK {
+			return signature, nil
+		}
+	}
+
+	return nil, errors.New("no candidate for the last byte was accepted, an earlier byte is wrong")
+}
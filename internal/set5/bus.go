@@ -0,0 +1,101 @@
+package set
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Message is what parties exchange over a Bus, the payload is one of the
+// protocol types like DHParams or EncryptedMessage.
+type Message struct {
+	From, To string
+	Payload  interface{}
+}
+
+// Interceptor sees every message before it is delivered and returns the
+// messages to deliver instead: nil drops the message, a modified copy changes
+// it and additional messages are injected.
+type Interceptor func(msg Message) []Message
+
+// Bus delivers messages in-process between named endpoints, parties run as
+// goroutines and block on Receive.
+type Bus struct {
+	mu          sync.Mutex
+	inboxes     map[string]chan Message
+	interceptor Interceptor
+}
+
+const inboxSize = 16
+
+// NewBus creates a bus, the interceptor may be nil for an honest network.
+func NewBus(interceptor Interceptor) *Bus {
+	return &Bus{
+		inboxes:     make(map[string]chan Message),
+		interceptor: interceptor,
+	}
+}
+
+type Endpoint struct {
+	name  string
+	bus   *Bus
+	inbox chan Message
+}
+
+// Endpoint registers a party with the given name or returns its existing endpoint
+func (b *Bus) Endpoint(name string) *Endpoint {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	inbox, ok := b.inboxes[name]
+	if !ok {
+		inbox = make(chan Message, inboxSize)
+		b.inboxes[name] = inbox
+	}
+
+	return &Endpoint{name: name, bus: b, inbox: inbox}
+}
+
+func (b *Bus) deliver(msg Message) error {
+
+	messages := []Message{msg}
+	if b.interceptor != nil {
+		messages = b.interceptor(msg)
+	}
+
+	for _, m := range messages {
+		b.mu.Lock()
+		inbox, ok := b.inboxes[m.To]
+		b.mu.Unlock()
+
+		if !ok {
+			return fmt.Errorf("unknown recipient %q", m.To)
+		}
+
+		select {
+		case inbox <- m:
+		default:
+			return fmt.Errorf("inbox of %q is full", m.To)
+		}
+	}
+
+	return nil
+}
+
+func (e *Endpoint) Name() string {
+	return e.name
+}
+
+func (e *Endpoint) Send(to string, payload interface{}) error {
+	return e.bus.deliver(Message{From: e.name, To: to, Payload: payload})
+}
+
+func (e *Endpoint) Receive(ctx context.Context) (Message, error) {
+	select {
+	case msg := <-e.inbox:
+		return msg, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
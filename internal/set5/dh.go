@@ -0,0 +1,103 @@
+package set
+
+import (
+	"crypto/rand"
+	"errors"
+	"hash"
+	"math/big"
+)
+
+const (
+	// RFC 3526 group 5, the prime of challenge 33
+	modp1536 = "ffffffffffffffffc90fdaa22168c234c4c6628b80dc1cd129024e088a67cc74" +
+		"020bbea63b139b22514a08798e3404ddef9519b3cd3a431b302b0a6df25f1437" +
+		"4fe1356d6d51c245e485b576625e7ec6f44c42e9a637ed6b0bff5cb6f406b7ed" +
+		"ee386bfb5a899fa5ae9f24117c4b1fe649286651ece45b3dc2007cb8a163bf05" +
+		"98da48361c55d39a69163fa8fd24cf5f83655d23dca3ad961c62f356208552bb" +
+		"9ed529077096966d670c354e4abc9804f1746c08ca237327ffffffffffffffff"
+
+	// RFC 3526 group 14
+	modp2048 = "ffffffffffffffffc90fdaa22168c234c4c6628b80dc1cd129024e088a67cc74" +
+		"020bbea63b139b22514a08798e3404ddef9519b3cd3a431b302b0a6df25f1437" +
+		"4fe1356d6d51c245e485b576625e7ec6f44c42e9a637ed6b0bff5cb6f406b7ed" +
+		"ee386bfb5a899fa5ae9f24117c4b1fe649286651ece45b3dc2007cb8a163bf05" +
+		"98da48361c55d39a69163fa8fd24cf5f83655d23dca3ad961c62f356208552bb" +
+		"9ed529077096966d670c354e4abc9804f1746c08ca18217c32905e462e36ce3b" +
+		"e39e772c180e86039b2783a2ec07a28fb5c55df06f4c52c9de2bcbf695581718" +
+		"3995497cea956ae515d2261898fa051015728e5a8aacaa68ffffffffffffffff"
+)
+
+type Group struct {
+	P, G *big.Int
+}
+
+func groupFromHex(p string, g int64) *Group {
+
+	prime, ok := new(big.Int).SetString(p, 16)
+
+	if !ok {
+		panic("Invalid group prime")
+	}
+
+	return &Group{P: prime, G: big.NewInt(g)}
+}
+
+func MODPGroup1536() *Group {
+	return groupFromHex(modp1536, 2)
+}
+
+func MODPGroup2048() *Group {
+	return groupFromHex(modp2048, 2)
+}
+
+// TinyGroup is the toy group of challenge 33, only useful for fast tests
+func TinyGroup() *Group {
+	return &Group{P: big.NewInt(37), G: big.NewInt(5)}
+}
+
+func ModExp(base, exponent, modulus *big.Int) *big.Int {
+	return new(big.Int).Exp(base, exponent, modulus)
+}
+
+type KeyPair struct {
+	Group   *Group
+	Private *big.Int
+	Public  *big.Int
+}
+
+// GenerateKeyPair chooses the private key uniformly from [1, p-1)
+func GenerateKeyPair(group *Group) (*KeyPair, error) {
+
+	if group.P.Cmp(big.NewInt(3)) < 0 {
+		return nil, errors.New("group prime too small")
+	}
+
+	upper := new(big.Int).Sub(group.P, big.NewInt(2))
+	private, err := rand.Int(rand.Reader, upper)
+
+	if err != nil {
+		return nil, err
+	}
+
+	private.Add(private, big.NewInt(1))
+
+	return &KeyPair{
+		Group:   group,
+		Private: private,
+		Public:  ModExp(group.G, private, group.P),
+	}, nil
+}
+
+// SharedSecret does not validate the public key of the peer on purpose, the
+// MITM challenges rely on degenerate values.
+func (kp *KeyPair) SharedSecret(peerPublic *big.Int) *big.Int {
+	return ModExp(peerPublic, kp.Private, kp.Group.P)
+}
+
+// DeriveAESKey hashes the big endian bytes of the secret and keeps the first
+// 16 bytes, e.g. with sha1.New as in challenge 34 or sha256.New.
+func DeriveAESKey(secret *big.Int, h func() hash.Hash) []byte {
+	d := h()
+	d.Write(secret.Bytes())
+	return d.Sum(nil)[:16]
+}
@@ -0,0 +1,46 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// HastadBroadcast recovers m from k >= e ciphertexts of the same message under
+// k different keys with exponent e. By CRT we get m^e mod N_1 * ... * N_k,
+// which is m^e itself since m < N_i, so the integer e-th root is m.
+func HastadBroadcast(ciphertexts []*big.Int, keys []*PublicKey) (*big.Int, error) {
+
+	if len(ciphertexts) != len(keys) || len(keys) == 0 {
+		return nil, errors.New("need one key per ciphertext")
+	}
+
+	e := keys[0].E
+	if e.Sign() <= 0 {
+		return nil, fmt.Errorf("exponent %s must be positive", e)
+	}
+
+	if !e.IsInt64() || e.Int64() > int64(len(keys)) {
+		return nil, fmt.Errorf("need at least e = %s ciphertexts, got %d", e, len(keys))
+	}
+
+	moduli := make([]*big.Int, len(keys))
+	for i, key := range keys {
+		if key.E.Cmp(e) != 0 {
+			return nil, errors.New("all keys need the same exponent")
+		}
+		moduli[i] = key.N
+	}
+
+	power, err := CRT(ciphertexts, moduli)
+	if err != nil {
+		return nil, err
+	}
+
+	m, exact := NthRoot(power, int(e.Int64()))
+	if !exact {
+		return nil, errors.New("result is not a perfect power, the message was not the same or too large")
+	}
+
+	return m, nil
+}
@@ -0,0 +1,139 @@
+package set
+
+import (
+	set2 "cryptopals/internal/set2"
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// Mallory sits on a Bus as Interceptor, relays the protocol with her
+// modifications and decrypts every EncryptedMessage she sees.
+type Mallory struct {
+	mu        sync.Mutex
+	modify    func(m *Mallory, msg Message) Message
+	p, g      *big.Int
+	publics   []*big.Int
+	secret    func(m *Mallory) (*big.Int, error)
+	recovered [][]byte
+	errs      []error
+}
+
+// Intercept is meant to be passed to NewBus
+func (m *Mallory) Intercept(msg Message) []Message {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if encrypted, ok := msg.Payload.(EncryptedMessage); ok {
+		m.decrypt(encrypted)
+		return []Message{msg}
+	}
+
+	return []Message{m.modify(m, msg)}
+}
+
+func (m *Mallory) decrypt(encrypted EncryptedMessage) {
+
+	secret, err := m.secret(m)
+	if err != nil {
+		m.errs = append(m.errs, err)
+		return
+	}
+
+	plaintext, err := set2.CBCDecrypt(encrypted.Ciphertext, sessionKey(secret))
+	if err != nil {
+		m.errs = append(m.errs, err)
+		return
+	}
+
+	m.recovered = append(m.recovered, plaintext)
+}
+
+// Recovered returns all decrypted messages in the order they were sent
+func (m *Mallory) Recovered() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]byte{}, m.recovered...)
+}
+
+// Err returns the first error Mallory ran into while decrypting
+func (m *Mallory) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.errs) > 0 {
+		return m.errs[0]
+	}
+	return nil
+}
+
+// NewParameterInjection replaces both public keys of challenge 34 with p, so
+// both parties end up with the shared secret p^x mod p = 0.
+func NewParameterInjection() *Mallory {
+	return &Mallory{
+		modify: func(m *Mallory, msg Message) Message {
+			switch payload := msg.Payload.(type) {
+			case DHParams:
+				m.p = payload.P
+				msg.Payload = DHParams{P: payload.P, G: payload.G, A: payload.P}
+			case DHPublic:
+				if m.p != nil {
+					msg.Payload = DHPublic{Key: m.p}
+				}
+			}
+			return msg
+		},
+		secret: func(m *Mallory) (*big.Int, error) {
+			return big.NewInt(0), nil
+		},
+	}
+}
+
+// NewMaliciousGroup replaces g of the challenge 35 group proposal by
+// choose(p). Since the initiator adopts the acknowledged group, the shared
+// secret is predictable for g = 1, g = p and g = p-1.
+func NewMaliciousGroup(choose func(p *big.Int) *big.Int) *Mallory {
+	return &Mallory{
+		modify: func(m *Mallory, msg Message) Message {
+			switch payload := msg.Payload.(type) {
+			case GroupProposal:
+				m.p = payload.P
+				m.g = choose(payload.P)
+				msg.Payload = GroupProposal{P: payload.P, G: m.g}
+			case DHPublic:
+				m.publics = append(m.publics, payload.Key)
+			}
+			return msg
+		},
+		secret: func(m *Mallory) (*big.Int, error) {
+			if m.p == nil || len(m.publics) < 2 {
+				return nil, errors.New("key exchange not observed")
+			}
+			return predictSecret(m.p, m.g, m.publics[0], m.publics[1])
+		},
+	}
+}
+
+// predictSecret returns s = A^b = B^a for the degenerate generators
+func predictSecret(p, g, a, b *big.Int) (*big.Int, error) {
+
+	one := big.NewInt(1)
+	pMinusOne := new(big.Int).Sub(p, one)
+
+	switch {
+	case g.Cmp(one) == 0:
+		// 1^x = 1
+		return one, nil
+	case new(big.Int).Mod(g, p).Sign() == 0:
+		// p^x = 0 mod p
+		return big.NewInt(0), nil
+	case g.Cmp(pMinusOne) == 0:
+		// (-1)^(xy) is -1 only if both exponents are odd, which the public keys reveal
+		if a.Cmp(pMinusOne) == 0 && b.Cmp(pMinusOne) == 0 {
+			return pMinusOne, nil
+		}
+		return one, nil
+	}
+
+	return nil, errors.New("shared secret not predictable for this generator")
+}
@@ -0,0 +1,158 @@
+package set
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// EGCD returns g = gcd(a, b) and x, y with ax + by = g
+func EGCD(a, b *big.Int) (g, x, y *big.Int) {
+
+	// invariants: oldR = a*oldS + b*oldT and r = a*s + b*t
+	oldR, r := new(big.Int).Set(a), new(big.Int).Set(b)
+	oldS, s := big.NewInt(1), big.NewInt(0)
+	oldT, t := big.NewInt(0), big.NewInt(1)
+
+	quotient := new(big.Int)
+	tmp := new(big.Int)
+
+	for r.Sign() != 0 {
+		quotient.Quo(oldR, r)
+
+		tmp.Mul(quotient, r)
+		oldR, r = r, new(big.Int).Sub(oldR, tmp)
+
+		tmp.Mul(quotient, s)
+		oldS, s = s, new(big.Int).Sub(oldS, tmp)
+
+		tmp.Mul(quotient, t)
+		oldT, t = t, new(big.Int).Sub(oldT, tmp)
+	}
+
+	// keep the gcd positive
+	if oldR.Sign() < 0 {
+		oldR.Neg(oldR)
+		oldS.Neg(oldS)
+		oldT.Neg(oldT)
+	}
+
+	return oldR, oldS, oldT
+}
+
+// InvMod returns x in [0, m) with ax = 1 mod m
+func InvMod(a, m *big.Int) (*big.Int, error) {
+
+	if m.Sign() <= 0 {
+		return nil, fmt.Errorf("modulus %s is not positive", m)
+	}
+
+	g, x, _ := EGCD(new(big.Int).Mod(a, m), m)
+
+	if g.Cmp(big.NewInt(1)) != 0 {
+		return nil, fmt.Errorf("%s is not invertible mod %s, gcd is %s", a, m, g)
+	}
+
+	return x.Mod(x, m), nil
+}
+
+// GeneratePrime returns a random prime with the two highest bits set, so the
+// product of two such primes has exactly twice the bits.
+func GeneratePrime(bits int) (*big.Int, error) {
+
+	if bits < 3 {
+		return nil, errors.New("prime size must be at least 3 bits")
+	}
+
+	bytes := make([]byte, (bits+7)/8)
+	candidate := new(big.Int)
+
+	for {
+		if _, err := rand.Reader.Read(bytes); err != nil {
+			return nil, err
+		}
+
+		candidate.SetBytes(bytes)
+		// drop the excess bits of the first byte
+		candidate.Rsh(candidate, uint(len(bytes)*8-bits))
+		candidate.SetBit(candidate, bits-1, 1)
+		candidate.SetBit(candidate, bits-2, 1)
+		candidate.SetBit(candidate, 0, 1)
+
+		if candidate.ProbablyPrime(20) {
+			return candidate, nil
+		}
+	}
+}
+
+// CRT returns the x in [0, M) with x = residues[i] mod moduli[i], where M is the
+// product of the pairwise coprime moduli.
+func CRT(residues, moduli []*big.Int) (*big.Int, error) {
+
+	if len(residues) != len(moduli) || len(moduli) == 0 {
+		return nil, errors.New("need the same non-zero number of residues and moduli")
+	}
+
+	product := big.NewInt(1)
+	for _, m := range moduli {
+		if m.Sign() <= 0 {
+			return nil, fmt.Errorf("modulus %s is not positive", m)
+		}
+		product.Mul(product, m)
+	}
+
+	result := new(big.Int)
+
+	for i, m := range moduli {
+		// ms = M / m_i, result += r_i * ms * invmod(ms, m_i)
+		ms := new(big.Int).Quo(product, m)
+
+		inverse, err := InvMod(ms, m)
+		if err != nil {
+			return nil, fmt.Errorf("moduli are not pairwise coprime: %w", err)
+		}
+
+		term := new(big.Int).Mul(residues[i], ms)
+		term.Mul(term, inverse)
+		result.Add(result, term)
+	}
+
+	return result.Mod(result, product), nil
+}
+
+// NthRoot returns the floor of the n-th root of x and whether it is exact.
+// It uses Newton's method on integers starting above the root, so it works for
+// numbers far beyond float64.
+func NthRoot(x *big.Int, n int) (root *big.Int, exact bool) {
+
+	if x.Sign() < 0 || n < 1 {
+		panic(fmt.Sprintf("No integer %d-th root of %s", n, x))
+	}
+
+	if x.Sign() == 0 || n == 1 {
+		return new(big.Int).Set(x), true
+	}
+
+	bigN := big.NewInt(int64(n))
+	nMinusOne := big.NewInt(int64(n - 1))
+
+	// 2^ceil(bits/n) is at least the root
+	current := new(big.Int).Lsh(big.NewInt(1), uint((x.BitLen()+n-1)/n))
+
+	for {
+		// next = ((n-1) * current + x / current^(n-1)) / n
+		next := new(big.Int).Exp(current, nMinusOne, nil)
+		next.Quo(x, next)
+		next.Add(next, new(big.Int).Mul(nMinusOne, current))
+		next.Quo(next, bigN)
+
+		if next.Cmp(current) >= 0 {
+			break
+		}
+
+		current = next
+	}
+
+	return current, new(big.Int).Exp(current, bigN, nil).Cmp(x) == 0
+}
@@ -0,0 +1,237 @@
+package set
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	set2 "cryptopals/internal/set2"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// DHParams is the first message of challenge 34: A->B p, g, A
+type DHParams struct {
+	P, G, A *big.Int
+}
+
+// DHPublic carries a public key, B->A B in challenge 34
+type DHPublic struct {
+	Key *big.Int
+}
+
+// GroupProposal is the first message of challenge 35: A->B p, g
+type GroupProposal struct {
+	P, G *big.Int
+}
+
+// GroupAck confirms the group the responder is going to use, the initiator
+// switches to it.
+type GroupAck struct {
+	P, G *big.Int
+}
+
+// EncryptedMessage is AES-CBC(SHA1(s)[0:16], msg) with the IV prepended
+type EncryptedMessage struct {
+	Ciphertext []byte
+}
+
+func sessionKey(secret *big.Int) []byte {
+	return DeriveAESKey(secret, sha1.New)
+}
+
+func receivePayload(ctx context.Context, ep *Endpoint) (interface{}, error) {
+	msg, err := ep.Receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Payload, nil
+}
+
+func unexpected(payload interface{}) error {
+	return fmt.Errorf("unexpected message %T", payload)
+}
+
+// echo sends message encrypted under the shared secret and checks the echo
+func echo(ctx context.Context, ep *Endpoint, peer string, secret *big.Int, message []byte) ([]byte, error) {
+
+	key := sessionKey(secret)
+
+	if err := ep.Send(peer, EncryptedMessage{Ciphertext: set2.CBCEncrypt(message, key)}); err != nil {
+		return nil, err
+	}
+
+	payload, err := receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, ok := payload.(EncryptedMessage)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	echoed, err := set2.CBCDecrypt(reply.Ciphertext, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(echoed, message) {
+		return echoed, errors.New("echo does not match the message")
+	}
+
+	return echoed, nil
+}
+
+// answerEcho decrypts one message and sends it back under a new IV
+func answerEcho(ctx context.Context, ep *Endpoint, peer string, secret *big.Int) ([]byte, error) {
+
+	payload, err := receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	request, ok := payload.(EncryptedMessage)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	key := sessionKey(secret)
+	message, err := set2.CBCDecrypt(request.Ciphertext, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, ep.Send(peer, EncryptedMessage{Ciphertext: set2.CBCEncrypt(message, key)})
+}
+
+// RunEchoInitiator is Alice of challenge 34, it returns the echoed message
+func RunEchoInitiator(ctx context.Context, ep *Endpoint, peer string, group *Group, message []byte) ([]byte, error) {
+
+	kp, err := GenerateKeyPair(group)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ep.Send(peer, DHParams{P: group.P, G: group.G, A: kp.Public}); err != nil {
+		return nil, err
+	}
+
+	payload, err := receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := payload.(DHPublic)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	return echo(ctx, ep, peer, kp.SharedSecret(public.Key), message)
+}
+
+// RunEchoResponder is Bob of challenge 34, it returns the received message
+func RunEchoResponder(ctx context.Context, ep *Endpoint, peer string) ([]byte, error) {
+
+	payload, err := receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	params, ok := payload.(DHParams)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	kp, err := GenerateKeyPair(&Group{P: params.P, G: params.G})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ep.Send(peer, DHPublic{Key: kp.Public}); err != nil {
+		return nil, err
+	}
+
+	return answerEcho(ctx, ep, peer, kp.SharedSecret(params.A))
+}
+
+// RunNegotiatedInitiator is Alice of challenge 35: she proposes a group and
+// uses whatever group the responder acknowledges.
+func RunNegotiatedInitiator(ctx context.Context, ep *Endpoint, peer string, group *Group, message []byte) ([]byte, error) {
+
+	if err := ep.Send(peer, GroupProposal{P: group.P, G: group.G}); err != nil {
+		return nil, err
+	}
+
+	payload, err := receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	ack, ok := payload.(GroupAck)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	kp, err := GenerateKeyPair(&Group{P: ack.P, G: ack.G})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ep.Send(peer, DHPublic{Key: kp.Public}); err != nil {
+		return nil, err
+	}
+
+	payload, err = receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := payload.(DHPublic)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	return echo(ctx, ep, peer, kp.SharedSecret(public.Key), message)
+}
+
+// RunNegotiatedResponder is Bob of challenge 35, it returns the received message
+func RunNegotiatedResponder(ctx context.Context, ep *Endpoint, peer string) ([]byte, error) {
+
+	payload, err := receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal, ok := payload.(GroupProposal)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	group := &Group{P: proposal.P, G: proposal.G}
+
+	if err := ep.Send(peer, GroupAck{P: group.P, G: group.G}); err != nil {
+		return nil, err
+	}
+
+	payload, err = receivePayload(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := payload.(DHPublic)
+	if !ok {
+		return nil, unexpected(payload)
+	}
+
+	kp, err := GenerateKeyPair(group)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ep.Send(peer, DHPublic{Key: kp.Public}); err != nil {
+		return nil, err
+	}
+
+	return answerEcho(ctx, ep, peer, kp.SharedSecret(public.Key))
+}
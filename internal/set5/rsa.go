@@ -0,0 +1,115 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// textbook RSA without any padding
+
+type PublicKey struct {
+	N, E *big.Int
+}
+
+type PrivateKey struct {
+	PublicKey
+	D    *big.Int
+	P, Q *big.Int
+}
+
+const maxKeyAttempts = 100
+
+// NewPrivateKey computes d = e^-1 mod (p-1)(q-1) and fails if e is not invertible
+func NewPrivateKey(p, q, e *big.Int) (*PrivateKey, error) {
+
+	one := big.NewInt(1)
+	et := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+
+	d, err := InvMod(e, et)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{
+		PublicKey: PublicKey{N: new(big.Int).Mul(p, q), E: new(big.Int).Set(e)},
+		D:         d,
+		P:         p,
+		Q:         q,
+	}, nil
+}
+
+const minKeyBits = 16
+
+// GenerateKey creates a key with a modulus of the given size. New primes are
+// drawn while e is not invertible, even exponents fail right away since
+// (p-1)(q-1) is always even. Both primes have their top two bits set, so with
+// less than 16 bits there are too few primes to pick two distinct ones.
+func GenerateKey(bits int, e *big.Int) (*PrivateKey, error) {
+
+	if e.Sign() <= 0 {
+		return nil, fmt.Errorf("exponent %s must be positive", e)
+	}
+
+	if e.Bit(0) == 0 {
+		return nil, fmt.Errorf("exponent %s is never invertible", e)
+	}
+
+	if bits < minKeyBits {
+		return nil, fmt.Errorf("modulus size must be at least %d bits", minKeyBits)
+	}
+
+	for attempt := 0; attempt < maxKeyAttempts; attempt++ {
+		p, err := GeneratePrime(bits / 2)
+		if err != nil {
+			return nil, err
+		}
+
+		q, err := GeneratePrime(bits - bits/2)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Cmp(q) == 0 {
+			continue
+		}
+
+		if key, err := NewPrivateKey(p, q, e); err == nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no primes found for exponent %s after %d attempts", e, maxKeyAttempts)
+}
+
+func (pub *PublicKey) checkRange(x *big.Int) error {
+	if x.Sign() < 0 || x.Cmp(pub.N) >= 0 {
+		return errors.New("value out of range [0, N)")
+	}
+	return nil
+}
+
+func (pub *PublicKey) Encrypt(m *big.Int) (*big.Int, error) {
+	if err := pub.checkRange(m); err != nil {
+		return nil, err
+	}
+	return ModExp(m, pub.E, pub.N), nil
+}
+
+func (priv *PrivateKey) Decrypt(c *big.Int) (*big.Int, error) {
+	if err := priv.checkRange(c); err != nil {
+		return nil, err
+	}
+	return ModExp(c, priv.D, priv.N), nil
+}
+
+func (priv *PrivateKey) Sign(m *big.Int) (*big.Int, error) {
+	return priv.Decrypt(m)
+}
+
+func (pub *PublicKey) Verify(m, signature *big.Int) bool {
+	if pub.checkRange(m) != nil || pub.checkRange(signature) != nil {
+		return false
+	}
+	return ModExp(signature, pub.E, pub.N).Cmp(m) == 0
+}
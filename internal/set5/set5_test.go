@@ -0,0 +1,642 @@
+package set_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	set "cryptopals/internal/set5"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDHGroups(t *testing.T) {
+
+	for _, group := range []*set.Group{set.TinyGroup(), set.MODPGroup1536(), set.MODPGroup2048()} {
+		if !group.P.ProbablyPrime(20) {
+			t.Errorf("p of %d bits is not prime", group.P.BitLen())
+		}
+
+		// the MODP groups are safe primes
+		if group.P.BitLen() > 8 {
+			q := new(big.Int).Rsh(group.P, 1)
+			if !q.ProbablyPrime(20) {
+				t.Errorf("p of %d bits is not a safe prime", group.P.BitLen())
+			}
+		}
+	}
+}
+
+func TestModExp(t *testing.T) {
+
+	if got := set.ModExp(big.NewInt(4), big.NewInt(13), big.NewInt(497)); got.Int64() != 445 {
+		t.Fatalf("Expected 445, but got %s", got)
+	}
+}
+
+func TestDHKeyExchange(t *testing.T) {
+
+	for _, group := range []*set.Group{set.TinyGroup(), set.MODPGroup1536()} {
+		alice, err := set.GenerateKeyPair(group)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bob, err := set.GenerateKeyPair(group)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s1 := alice.SharedSecret(bob.Public)
+		s2 := bob.SharedSecret(alice.Public)
+
+		if s1.Cmp(s2) != 0 {
+			t.Fatalf("Shared secrets differ %s != %s", s1, s2)
+		}
+
+		for _, h := range []func() []byte{
+			func() []byte { return set.DeriveAESKey(s1, sha1.New) },
+			func() []byte { return set.DeriveAESKey(s1, sha256.New) },
+		} {
+			if key := h(); len(key) != 16 {
+				t.Fatalf("Expected AES-128 key, got %d bytes", len(key))
+			}
+		}
+
+		if !bytes.Equal(set.DeriveAESKey(s1, sha256.New), set.DeriveAESKey(s2, sha256.New)) {
+			t.Fatal("Derived keys differ")
+		}
+	}
+}
+
+type protocolRun func(ctx context.Context, bus *set.Bus, message []byte) (echoed, received []byte, err error)
+
+func runEcho(ctx context.Context, bus *set.Bus, message []byte) ([]byte, []byte, error) {
+	return runParties(ctx, bus, func(ctx context.Context, alice *set.Endpoint) ([]byte, error) {
+		return set.RunEchoInitiator(ctx, alice, "bob", set.MODPGroup1536(), message)
+	}, func(ctx context.Context, bob *set.Endpoint) ([]byte, error) {
+		return set.RunEchoResponder(ctx, bob, "alice")
+	})
+}
+
+func runNegotiated(ctx context.Context, bus *set.Bus, message []byte) ([]byte, []byte, error) {
+	return runParties(ctx, bus, func(ctx context.Context, alice *set.Endpoint) ([]byte, error) {
+		return set.RunNegotiatedInitiator(ctx, alice, "bob", set.MODPGroup1536(), message)
+	}, func(ctx context.Context, bob *set.Endpoint) ([]byte, error) {
+		return set.RunNegotiatedResponder(ctx, bob, "alice")
+	})
+}
+
+func runParties(ctx context.Context, bus *set.Bus,
+	alice func(context.Context, *set.Endpoint) ([]byte, error),
+	bob func(context.Context, *set.Endpoint) ([]byte, error)) ([]byte, []byte, error) {
+
+	aliceEndpoint, bobEndpoint := bus.Endpoint("alice"), bus.Endpoint("bob")
+
+	type result struct {
+		message []byte
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		received, err := bob(ctx, bobEndpoint)
+		done <- result{received, err}
+	}()
+
+	echoed, err := alice(ctx, aliceEndpoint)
+	bobResult := <-done
+
+	if err == nil {
+		err = bobResult.err
+	}
+
+	return echoed, bobResult.message, err
+}
+
+func TestEchoProtocols(t *testing.T) {
+
+	for name, run := range map[string]protocolRun{"echo": runEcho, "negotiated": runNegotiated} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			message := []byte("YELLOW SUBMARINE")
+			echoed, received, err := run(ctx, set.NewBus(nil), message)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(echoed, message) || !bytes.Equal(received, message) {
+				t.Fatalf("Expected %s, got echo %s and received %s", message, echoed, received)
+			}
+		})
+	}
+}
+
+func TestBusInterceptor(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// drop everything to bob and inject a copy to mallory instead
+	bus := set.NewBus(func(msg set.Message) []set.Message {
+		if msg.To == "bob" {
+			msg.To = "mallory"
+			return []set.Message{msg}
+		}
+		return nil
+	})
+
+	alice, bob, mallory := bus.Endpoint("alice"), bus.Endpoint("bob"), bus.Endpoint("mallory")
+
+	if err := alice.Send("bob", set.DHPublic{Key: big.NewInt(42)}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mallory.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.From != "alice" || msg.Payload.(set.DHPublic).Key.Int64() != 42 {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+
+	if err := bob.Send("alice", set.DHPublic{Key: big.NewInt(1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+
+	if _, err := alice.Receive(short); err == nil {
+		t.Fatal("Dropped message was delivered")
+	}
+
+	if err := set.NewBus(nil).Endpoint("alice").Send("nobody", set.DHPublic{}); err == nil {
+		t.Fatal("Expected error for unknown recipient")
+	}
+}
+
+func TestParameterInjection(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mallory := set.NewParameterInjection()
+	message := []byte("Attack at dawn")
+
+	if _, _, err := runEcho(ctx, set.NewBus(mallory.Intercept), message); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mallory.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := mallory.Recovered()
+	if len(recovered) != 2 || !bytes.Equal(recovered[0], message) || !bytes.Equal(recovered[1], message) {
+		t.Fatalf("Mallory recovered %q", recovered)
+	}
+}
+
+func TestMaliciousGroup(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		choose func(p *big.Int) *big.Int
+	}{
+		{name: "g = 1", choose: func(p *big.Int) *big.Int { return big.NewInt(1) }},
+		{name: "g = p", choose: func(p *big.Int) *big.Int { return new(big.Int).Set(p) }},
+		{name: "g = p - 1", choose: func(p *big.Int) *big.Int { return new(big.Int).Sub(p, big.NewInt(1)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// repeat to cover the different parities of the private keys
+			for i := 0; i < 4; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				mallory := set.NewMaliciousGroup(tt.choose)
+				message := []byte("Attack at dawn")
+
+				if _, _, err := runNegotiated(ctx, set.NewBus(mallory.Intercept), message); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := mallory.Err(); err != nil {
+					t.Fatal(err)
+				}
+
+				recovered := mallory.Recovered()
+				if len(recovered) != 2 || !bytes.Equal(recovered[0], message) || !bytes.Equal(recovered[1], message) {
+					t.Fatalf("Mallory recovered %q", recovered)
+				}
+			}
+		})
+	}
+}
+
+func TestSRP(t *testing.T) {
+
+	server := httptest.NewServer(set.CreateSRPHandler(set.DefaultSRPParams()))
+	defer server.Close()
+
+	client := &set.SRPClient{Params: set.DefaultSRPParams(), Client: server.Client(), BaseURL: server.URL}
+
+	if err := client.Register("alice@example.com", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Register("alice@example.com", "other"); err == nil {
+		t.Fatal("Registered the same user twice")
+	}
+
+	if err := client.Login("alice@example.com", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Login("alice@example.com", "hunter3"); !errors.Is(err, set.ErrLoginFailed) {
+		t.Fatalf("Expected failed login, got %v", err)
+	}
+
+	if err := client.Login("bob@example.com", "hunter2"); err == nil {
+		t.Fatal("Logged in as unknown user")
+	}
+}
+
+func TestSRPConcurrentSessions(t *testing.T) {
+
+	server := httptest.NewServer(set.CreateSRPHandler(set.DefaultSRPParams()))
+	defer server.Close()
+
+	client := &set.SRPClient{Params: set.DefaultSRPParams(), Client: server.Client(), BaseURL: server.URL}
+
+	const users = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*users)
+
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			email, password := fmt.Sprintf("user%d@example.com", i), fmt.Sprintf("password%d", i)
+
+			if err := client.Register(email, password); err != nil {
+				errs <- err
+				return
+			}
+
+			// two sessions of the same user at the same time
+			var inner sync.WaitGroup
+			for j := 0; j < 2; j++ {
+				inner.Add(1)
+				go func() {
+					defer inner.Done()
+					if err := client.Login(email, password); err != nil {
+						errs <- err
+					}
+				}()
+			}
+			inner.Wait()
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestLoginWithoutPassword(t *testing.T) {
+
+	server := httptest.NewServer(set.CreateSRPHandler(set.DefaultSRPParams()))
+	defer server.Close()
+
+	client := &set.SRPClient{Params: set.DefaultSRPParams(), Client: server.Client(), BaseURL: server.URL}
+
+	if err := client.Register("alice@example.com", "correct horse battery staple"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, multiple := range []int64{0, 1, 2} {
+		if err := set.LoginWithoutPassword(client, "alice@example.com", multiple); err != nil {
+			t.Errorf("A = %d * N: %v", multiple, err)
+		}
+	}
+}
+
+func TestSimplifiedSRP(t *testing.T) {
+
+	params := set.DefaultSRPParams()
+	server := set.NewSimplifiedSRPServer(params)
+
+	if err := server.Register("alice@example.com", "submarine123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := set.SimplifiedSRPLogin(server, params, "alice@example.com", "submarine123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := set.SimplifiedSRPLogin(server, params, "alice@example.com", "submarine124"); !errors.Is(err, set.ErrLoginFailed) {
+		t.Fatalf("Expected failed login, got %v", err)
+	}
+}
+
+func TestSimplifiedSRPDictionaryAttack(t *testing.T) {
+
+	params := set.DefaultSRPParams()
+	mallory := set.NewSimplifiedSRPMITM(params)
+
+	if err := set.SimplifiedSRPLogin(mallory, params, "alice@example.com", "submarine123"); err != nil {
+		t.Fatal(err)
+	}
+
+	capture, err := mallory.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := set.CrackSimplifiedSRPFile(capture, "testdata/wordlist.txt", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Found || result.Password != "submarine123" {
+		t.Fatalf("Expected submarine123, got %+v", result)
+	}
+
+	t.Logf("Cracked after %d passwords in %s (%.0f passwords/s)", result.Tried, result.Elapsed, result.Throughput)
+
+	// a password outside of the wordlist is not found
+	mallory = set.NewSimplifiedSRPMITM(params)
+	if err := set.SimplifiedSRPLogin(mallory, params, "alice@example.com", "not in the list"); err != nil {
+		t.Fatal(err)
+	}
+
+	capture, _ = mallory.Capture()
+	result, err = set.CrackSimplifiedSRPFile(capture, "testdata/wordlist.txt", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Found || result.Tried != 620 {
+		t.Fatalf("Expected no password after 620 tries, got %+v", result)
+	}
+}
+
+func TestInvMod(t *testing.T) {
+
+	tests := []struct {
+		a, m    int64
+		want    int64
+		wantErr bool
+	}{
+		{a: 17, m: 3120, want: 2753},
+		{a: 3, m: 11, want: 4},
+		{a: -3, m: 11, want: 7},
+		{a: 2, m: 4, wantErr: true},
+		{a: 3, m: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := set.InvMod(big.NewInt(tt.a), big.NewInt(tt.m))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("InvMod(%d, %d) error = %v, wantErr %v", tt.a, tt.m, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.Int64() != tt.want {
+			t.Errorf("InvMod(%d, %d) = %s, want %d", tt.a, tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestEGCD(t *testing.T) {
+
+	a, b := big.NewInt(240), big.NewInt(46)
+	g, x, y := set.EGCD(a, b)
+
+	// 240x + 46y = 2
+	check := new(big.Int).Add(new(big.Int).Mul(a, x), new(big.Int).Mul(b, y))
+
+	if g.Int64() != 2 || check.Cmp(g) != 0 {
+		t.Fatalf("Got gcd %s with %s * 240 + %s * 46 = %s", g, x, y, check)
+	}
+}
+
+func TestRSA(t *testing.T) {
+
+	for _, tt := range []struct {
+		bits int
+		e    int64
+	}{{64, 3}, {1024, 3}, {1024, 65537}, {2048, 3}} {
+		key, err := set.GenerateKey(tt.bits, big.NewInt(tt.e))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if key.N.BitLen() != tt.bits {
+			t.Errorf("Expected %d bit modulus, got %d", tt.bits, key.N.BitLen())
+		}
+
+		m := big.NewInt(42)
+		c, err := key.Encrypt(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decrypted, err := key.Decrypt(c); err != nil || decrypted.Cmp(m) != 0 {
+			t.Fatalf("Expected 42, got %s (%v)", decrypted, err)
+		}
+
+		signature, err := key.Sign(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !key.Verify(m, signature) || key.Verify(big.NewInt(43), signature) {
+			t.Fatal("Signature verification is broken")
+		}
+	}
+}
+
+func TestRSAWeirdParameters(t *testing.T) {
+
+	// (11-1)(13-1) = 120 shares the factor 3 with e
+	if _, err := set.NewPrivateKey(big.NewInt(11), big.NewInt(13), big.NewInt(3)); err == nil {
+		t.Error("Expected error for non-invertible e")
+	}
+
+	if _, err := set.GenerateKey(512, big.NewInt(4)); err == nil {
+		t.Error("Expected error for even e")
+	}
+
+	if _, err := set.GenerateKey(512, big.NewInt(0)); err == nil {
+		t.Error("Expected error for e = 0")
+	}
+
+	if _, err := set.GenerateKey(8, big.NewInt(3)); err == nil {
+		t.Error("Expected error for a modulus too small for two distinct primes")
+	}
+
+	// e = 1 is invertible with d = 1, which makes encryption the identity
+	identity, err := set.GenerateKey(16, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := identity.Encrypt(big.NewInt(42)); err != nil || c.Int64() != 42 || identity.D.Int64() != 1 {
+		t.Errorf("Expected the identity for e = 1, got %s with d = %s (%v)", c, identity.D, err)
+	}
+
+	key, err := set.NewPrivateKey(big.NewInt(11), big.NewInt(17), big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := key.Encrypt(key.N); err == nil {
+		t.Error("Expected error for message not smaller than N")
+	}
+}
+
+func TestCRT(t *testing.T) {
+
+	// x = 2 mod 3, x = 3 mod 5, x = 2 mod 7 => x = 23
+	ints := func(xs ...int64) []*big.Int {
+		result := make([]*big.Int, len(xs))
+		for i, x := range xs {
+			result[i] = big.NewInt(x)
+		}
+		return result
+	}
+
+	x, err := set.CRT(ints(2, 3, 2), ints(3, 5, 7))
+	if err != nil || x.Int64() != 23 {
+		t.Fatalf("Expected 23, got %s (%v)", x, err)
+	}
+
+	if _, err := set.CRT(ints(1, 1), ints(4, 6)); err == nil {
+		t.Fatal("Expected error for moduli that are not coprime")
+	}
+
+	// large moduli
+	moduli := make([]*big.Int, 3)
+	for i := range moduli {
+		p, err := set.GeneratePrime(512)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moduli[i] = p
+	}
+
+	// a secret in [2^1024, m1 * m2 * m3) exceeds every single modulus, so
+	// no residue equals the secret and all of them are needed
+	product := new(big.Int).Mul(moduli[0], moduli[1])
+	product.Mul(product, moduli[2])
+	lower := new(big.Int).Lsh(big.NewInt(1), 1024)
+
+	secret, err := rand.Int(rand.Reader, new(big.Int).Sub(product, lower))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.Add(secret, lower)
+
+	residues := make([]*big.Int, 3)
+	for i, m := range moduli {
+		residues[i] = new(big.Int).Mod(secret, m)
+		if residues[i].Cmp(secret) == 0 {
+			t.Fatalf("Secret %s is smaller than modulus %d", secret, i)
+		}
+	}
+
+	if x, err := set.CRT(residues, moduli); err != nil || x.Cmp(secret) != 0 {
+		t.Fatalf("Expected %s, got %s (%v)", secret, x, err)
+	}
+}
+
+func TestNthRoot(t *testing.T) {
+
+	base, _ := new(big.Int).SetString("98765432109876543210987654321098765432109876543210987654321", 10)
+
+	for _, n := range []int{1, 2, 3, 5, 17} {
+		power := new(big.Int).Exp(base, big.NewInt(int64(n)), nil)
+
+		if root, exact := set.NthRoot(power, n); !exact || root.Cmp(base) != 0 {
+			t.Errorf("%d-th root: expected %s, got %s (exact %v)", n, base, root, exact)
+		}
+
+		// one more is not a perfect power anymore, but the floor stays
+		power.Add(power, big.NewInt(1))
+
+		if root, exact := set.NthRoot(power, n); n > 1 && (exact || root.Cmp(base) != 0) {
+			t.Errorf("%d-th root of power + 1: got %s (exact %v)", n, root, exact)
+		}
+	}
+
+	for x := int64(0); x < 100; x++ {
+		root, _ := set.NthRoot(big.NewInt(x), 3)
+		r := root.Int64()
+		if r*r*r > x || (r+1)*(r+1)*(r+1) <= x {
+			t.Errorf("Cube root of %d is not %d", x, r)
+		}
+	}
+}
+
+func TestHastadBroadcast(t *testing.T) {
+
+	message := new(big.Int).SetBytes([]byte("Attack at dawn, but broadcast it three times"))
+	e := big.NewInt(3)
+
+	// one more ciphertext than needed
+	ciphertexts := make([]*big.Int, 4)
+	keys := make([]*set.PublicKey, 4)
+
+	for i := range keys {
+		key, err := set.GenerateKey(1024, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = &key.PublicKey
+
+		if ciphertexts[i], err = key.Encrypt(message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recovered, err := set.HastadBroadcast(ciphertexts, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recovered.Cmp(message) != 0 {
+		t.Fatalf("Expected %s, got %s", message.Bytes(), recovered.Bytes())
+	}
+
+	if recovered, err := set.HastadBroadcast(ciphertexts[1:], keys[1:]); err != nil || recovered.Cmp(message) != 0 {
+		t.Fatalf("Expected %s from exactly e ciphertexts, got %v (%v)", message.Bytes(), recovered, err)
+	}
+
+	for _, e := range []int64{0, -3} {
+		weird := []*set.PublicKey{{N: keys[0].N, E: big.NewInt(e)}}
+		if _, err := set.HastadBroadcast(ciphertexts[:1], weird); err == nil {
+			t.Fatalf("Expected error for e = %d", e)
+		}
+	}
+
+	if _, err := set.HastadBroadcast(ciphertexts[:2], keys[:2]); err == nil {
+		t.Fatal("Expected error for too few ciphertexts")
+	}
+}
@@ -0,0 +1,413 @@
+package set
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	set4 "cryptopals/internal/set4"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+)
+
+// SRPParams are the public parameters of SRP-6a, H is SHA-256
+type SRPParams struct {
+	N, G *big.Int
+}
+
+// DefaultSRPParams uses the NIST prime of challenge 33 with g = 2
+func DefaultSRPParams() *SRPParams {
+	group := MODPGroup1536()
+	return &SRPParams{N: group.P, G: group.G}
+}
+
+// pad left pads to the length of N, values that do not fit are left as they
+// are since the server does not reject A >= N
+func (p *SRPParams) pad(x *big.Int) []byte {
+	size := (p.N.BitLen() + 7) / 8
+	if (x.BitLen()+7)/8 > size {
+		return x.Bytes()
+	}
+	return x.FillBytes(make([]byte, size))
+}
+
+func srpHash(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func srpHashInt(parts ...[]byte) *big.Int {
+	return new(big.Int).SetBytes(srpHash(parts...))
+}
+
+// k = H(N | PAD(g)) as in RFC 5054
+func (p *SRPParams) k() *big.Int {
+	return srpHashInt(p.N.Bytes(), p.pad(p.G))
+}
+
+// u = H(PAD(A) | PAD(B))
+func (p *SRPParams) u(A, B *big.Int) *big.Int {
+	return srpHashInt(p.pad(A), p.pad(B))
+}
+
+// x = H(salt | H(email | ":" | password))
+func srpX(salt []byte, email, password string) *big.Int {
+	return srpHashInt(salt, srpHash([]byte(email+":"+password)))
+}
+
+func (p *SRPParams) Verifier(salt []byte, email, password string) *big.Int {
+	return ModExp(p.G, srpX(salt, email, password), p.N)
+}
+
+// SessionKey is K = H(S)
+func SessionKey(S *big.Int) []byte {
+	return srpHash(S.Bytes())
+}
+
+// Proof is the HMAC-SHA256 of the salt under K that the client sends
+func Proof(K, salt []byte) []byte {
+	return (&set4.HMACVerifier{Hash: sha256.New, Key: K}).Sign(salt)
+}
+
+func randomExponent(N *big.Int) (*big.Int, error) {
+	x, err := rand.Int(rand.Reader, N)
+	if err != nil {
+		return nil, err
+	}
+	return x.Add(x, big.NewInt(1)), nil
+}
+
+type srpUser struct {
+	salt     []byte
+	verifier *big.Int
+}
+
+type srpSession struct {
+	email string
+	salt  []byte
+	key   []byte
+}
+
+type SRPHandler struct {
+	params   *SRPParams
+	mu       sync.Mutex
+	users    map[string]srpUser
+	sessions map[string]srpSession
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Salt     string `json:"salt"`
+	Verifier string `json:"verifier"`
+}
+
+type StartLoginRequest struct {
+	Email string `json:"email"`
+	A     string `json:"A"`
+}
+
+type StartLoginResponse struct {
+	Session string `json:"session"`
+	Salt    string `json:"salt"`
+	B       string `json:"B"`
+}
+
+type VerifyLoginRequest struct {
+	Session string `json:"session"`
+	Proof   string `json:"proof"`
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
+
+func (srp *SRPHandler) register(w http.ResponseWriter, r *http.Request) {
+
+	var req RegisterRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	salt, err := hex.DecodeString(req.Salt)
+	verifier, ok := new(big.Int).SetString(req.Verifier, 16)
+
+	if err != nil || !ok || req.Email == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	srp.mu.Lock()
+	defer srp.mu.Unlock()
+
+	if _, exists := srp.users[req.Email]; exists {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	srp.users[req.Email] = srpUser{salt: salt, verifier: verifier}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (srp *SRPHandler) startLogin(w http.ResponseWriter, r *http.Request) {
+
+	var req StartLoginRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// A is not checked for A % N == 0 on purpose, see challenge 37
+	A, ok := new(big.Int).SetString(req.A, 16)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	srp.mu.Lock()
+	user, exists := srp.users[req.Email]
+	srp.mu.Unlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	b, err := randomExponent(srp.params.N)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	N := srp.params.N
+
+	// B = kv + g^b
+	B := new(big.Int).Mul(srp.params.k(), user.verifier)
+	B.Add(B, ModExp(srp.params.G, b, N))
+	B.Mod(B, N)
+
+	// S = (A * v^u)^b
+	S := ModExp(user.verifier, srp.params.u(A, B), N)
+	S.Mul(S, A)
+	S = ModExp(S.Mod(S, N), b, N)
+
+	id := make([]byte, 16)
+	if _, err := rand.Reader.Read(id); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	session := hex.EncodeToString(id)
+
+	srp.mu.Lock()
+	srp.sessions[session] = srpSession{email: req.Email, salt: user.salt, key: SessionKey(S)}
+	srp.mu.Unlock()
+
+	writeJSON(w, StartLoginResponse{
+		Session: session,
+		Salt:    hex.EncodeToString(user.salt),
+		B:       B.Text(16),
+	})
+}
+
+func (srp *SRPHandler) verifyLogin(w http.ResponseWriter, r *http.Request) {
+
+	var req VerifyLoginRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	proof, err := hex.DecodeString(req.Proof)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// a session can only be tried once
+	srp.mu.Lock()
+	session, exists := srp.sessions[req.Session]
+	delete(srp.sessions, req.Session)
+	srp.mu.Unlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	verifier := &set4.HMACVerifier{Hash: sha256.New, Key: session.key}
+
+	if verifier.Verify(session.salt, proof) {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusForbidden)
+	}
+}
+
+// CreateSRPHandler serves /register, /login/start and /login/verify with JSON
+// bodies. Users and sessions are kept in memory.
+func CreateSRPHandler(params *SRPParams) *http.ServeMux {
+
+	handler := &SRPHandler{
+		params:   params,
+		users:    make(map[string]srpUser),
+		sessions: make(map[string]srpSession),
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/register", handler.register)
+	mux.HandleFunc("/login/start", handler.startLogin)
+	mux.HandleFunc("/login/verify", handler.verifyLogin)
+
+	return mux
+}
+
+var ErrLoginFailed = errors.New("login failed")
+
+type SRPClient struct {
+	Params  *SRPParams
+	Client  *http.Client
+	BaseURL string
+}
+
+func (c *SRPClient) post(path string, body, response interface{}) (int, error) {
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.Client.Post(c.BaseURL+path, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if response != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (c *SRPClient) Register(email, password string) error {
+
+	salt := make([]byte, 16)
+	if _, err := rand.Reader.Read(salt); err != nil {
+		return err
+	}
+
+	status, err := c.post("/register", RegisterRequest{
+		Email:    email,
+		Salt:     hex.EncodeToString(salt),
+		Verifier: c.Params.Verifier(salt, email, password).Text(16),
+	}, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusCreated {
+		return fmt.Errorf("registration failed with status %d", status)
+	}
+
+	return nil
+}
+
+// StartLogin sends an arbitrary A, which allows the attacks of challenge 37
+func (c *SRPClient) StartLogin(email string, A *big.Int) (session string, salt []byte, B *big.Int, err error) {
+
+	var resp StartLoginResponse
+	status, err := c.post("/login/start", StartLoginRequest{Email: email, A: A.Text(16)}, &resp)
+
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if status != http.StatusOK {
+		return "", nil, nil, fmt.Errorf("login start failed with status %d", status)
+	}
+
+	salt, err = hex.DecodeString(resp.Salt)
+	B, ok := new(big.Int).SetString(resp.B, 16)
+
+	if err != nil || !ok {
+		return "", nil, nil, errors.New("malformed login response")
+	}
+
+	return resp.Session, salt, B, nil
+}
+
+// VerifyLogin returns ErrLoginFailed if the server rejects the proof
+func (c *SRPClient) VerifyLogin(session string, proof []byte) error {
+
+	status, err := c.post("/login/verify", VerifyLoginRequest{Session: session, Proof: hex.EncodeToString(proof)}, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return ErrLoginFailed
+	}
+
+	return nil
+}
+
+func (c *SRPClient) Login(email, password string) error {
+
+	N := c.Params.N
+
+	a, err := randomExponent(N)
+	if err != nil {
+		return err
+	}
+
+	A := ModExp(c.Params.G, a, N)
+
+	session, salt, B, err := c.StartLogin(email, A)
+	if err != nil {
+		return err
+	}
+
+	u := c.Params.u(A, B)
+	x := srpX(salt, email, password)
+
+	// S = (B - k * g^x)^(a + u * x)
+	base := new(big.Int).Mul(c.Params.k(), ModExp(c.Params.G, x, N))
+	base.Sub(B, base)
+	base.Mod(base, N)
+
+	exponent := new(big.Int).Mul(u, x)
+	exponent.Add(exponent, a)
+
+	S := ModExp(base, exponent, N)
+
+	return c.VerifyLogin(session, Proof(SessionKey(S), salt))
+}
@@ -0,0 +1,311 @@
+package set
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	set4 "cryptopals/internal/set4"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// LoginWithoutPassword sends A = multiple * N, so the server computes
+// S = (A * v^u)^b = 0 mod N and the session key is known without a password.
+func LoginWithoutPassword(client *SRPClient, email string, multiple int64) error {
+
+	A := new(big.Int).Mul(big.NewInt(multiple), client.Params.N)
+
+	session, salt, _, err := client.StartLogin(email, A)
+	if err != nil {
+		return err
+	}
+
+	return client.VerifyLogin(session, Proof(SessionKey(big.NewInt(0)), salt))
+}
+
+/*
+	Simplified SRP of challenge 38 without k:
+
+	C->S	I, A = g^a
+	S->C	salt, B = g^b, u 128 bit random
+	C		x = SHA256(salt | password), S = B^(a + ux), K = SHA256(S)
+	C->S	HMAC-SHA256(K, salt)
+	S		S = (A * v^u)^b
+*/
+
+// SimplifiedSRPServer is the server side of simplified SRP, which can be an
+// honest server or Mallory pretending to be one.
+type SimplifiedSRPServer interface {
+	Start(email string, A *big.Int) (salt []byte, B, u *big.Int, err error)
+	Verify(email string, proof []byte) bool
+}
+
+func simplifiedX(salt []byte, password string) *big.Int {
+	return srpHashInt(salt, []byte(password))
+}
+
+type simplifiedUser struct {
+	salt     []byte
+	verifier *big.Int
+	// state of the pending login
+	key []byte
+}
+
+type HonestSimplifiedSRPServer struct {
+	params *SRPParams
+	mu     sync.Mutex
+	users  map[string]*simplifiedUser
+}
+
+func NewSimplifiedSRPServer(params *SRPParams) *HonestSimplifiedSRPServer {
+	return &HonestSimplifiedSRPServer{params: params, users: make(map[string]*simplifiedUser)}
+}
+
+func (s *HonestSimplifiedSRPServer) Register(email, password string) error {
+
+	salt := make([]byte, 16)
+	if _, err := rand.Reader.Read(salt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[email] = &simplifiedUser{
+		salt:     salt,
+		verifier: ModExp(s.params.G, simplifiedX(salt, password), s.params.N),
+	}
+
+	return nil
+}
+
+func (s *HonestSimplifiedSRPServer) Start(email string, A *big.Int) ([]byte, *big.Int, *big.Int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[email]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unknown user %q", email)
+	}
+
+	N := s.params.N
+
+	b, err := randomExponent(N)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	u, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// S = (A * v^u)^b
+	S := ModExp(user.verifier, u, N)
+	S.Mul(S, A)
+	S = ModExp(S.Mod(S, N), b, N)
+	user.key = SessionKey(S)
+
+	return user.salt, ModExp(s.params.G, b, N), u, nil
+}
+
+func (s *HonestSimplifiedSRPServer) Verify(email string, proof []byte) bool {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[email]
+	if !ok || user.key == nil {
+		return false
+	}
+
+	verifier := &set4.HMACVerifier{Hash: sha256.New, Key: user.key}
+	user.key = nil
+
+	return verifier.Verify(user.salt, proof)
+}
+
+// SimplifiedSRPLogin is the client of simplified SRP
+func SimplifiedSRPLogin(server SimplifiedSRPServer, params *SRPParams, email, password string) error {
+
+	N := params.N
+
+	a, err := randomExponent(N)
+	if err != nil {
+		return err
+	}
+
+	salt, B, u, err := server.Start(email, ModExp(params.G, a, N))
+	if err != nil {
+		return err
+	}
+
+	// S = B^(a + ux)
+	exponent := new(big.Int).Mul(u, simplifiedX(salt, password))
+	exponent.Add(exponent, a)
+
+	if !server.Verify(email, Proof(SessionKey(ModExp(B, exponent, N)), salt)) {
+		return ErrLoginFailed
+	}
+
+	return nil
+}
+
+// SimplifiedSRPCapture holds what Mallory needs to crack the password offline
+type SimplifiedSRPCapture struct {
+	Params *SRPParams
+	Salt   []byte
+	A      *big.Int
+	Proof  []byte
+}
+
+// SimplifiedSRPMITM pretends to be the server with b = 1, B = g and u = 1,
+// which makes S = A * v mod N only depend on the password.
+type SimplifiedSRPMITM struct {
+	params *SRPParams
+	salt   []byte
+	mu     sync.Mutex
+	public *big.Int
+	proof  []byte
+}
+
+func NewSimplifiedSRPMITM(params *SRPParams) *SimplifiedSRPMITM {
+	return &SimplifiedSRPMITM{params: params, salt: []byte{}}
+}
+
+func (m *SimplifiedSRPMITM) Start(email string, A *big.Int) ([]byte, *big.Int, *big.Int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.public = A
+	return m.salt, m.params.G, big.NewInt(1), nil
+}
+
+// Verify captures the proof and lets the client in to not raise suspicion
+func (m *SimplifiedSRPMITM) Verify(email string, proof []byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.proof = proof
+	return true
+}
+
+func (m *SimplifiedSRPMITM) Capture() (*SimplifiedSRPCapture, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.public == nil || m.proof == nil {
+		return nil, errors.New("no login captured")
+	}
+
+	return &SimplifiedSRPCapture{Params: m.params, Salt: m.salt, A: m.public, Proof: m.proof}, nil
+}
+
+// matches checks a password guess against the capture with b = 1 and u = 1
+func (c *SimplifiedSRPCapture) matches(password string) bool {
+
+	N := c.Params.N
+	v := ModExp(c.Params.G, simplifiedX(c.Salt, password), N)
+
+	S := new(big.Int).Mul(c.A, v)
+	S.Mod(S, N)
+
+	verifier := &set4.HMACVerifier{Hash: sha256.New, Key: SessionKey(S)}
+	return verifier.Verify(c.Salt, c.Proof)
+}
+
+type CrackResult struct {
+	Password string
+	Found    bool
+	// number of passwords tested
+	Tried      int
+	Elapsed    time.Duration
+	Throughput float64 // passwords per second
+}
+
+// CrackSimplifiedSRP tries every line of the wordlist with a pool of workers
+func CrackSimplifiedSRP(capture *SimplifiedSRPCapture, wordlist io.Reader, workers int) (*CrackResult, error) {
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	start := time.Now()
+	words := make(chan string)
+	done := make(chan struct{})
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		tried    int
+		password string
+		found    bool
+		once     sync.Once
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for word := range words {
+				match := capture.matches(word)
+
+				mu.Lock()
+				tried++
+				if match && !found {
+					password, found = word, true
+				}
+				mu.Unlock()
+
+				if match {
+					once.Do(func() { close(done) })
+				}
+			}
+		}()
+	}
+
+	scanner := bufio.NewScanner(wordlist)
+	scanner.Split(bufio.ScanLines)
+
+feed:
+	for scanner.Scan() {
+		select {
+		case words <- scanner.Text():
+		case <-done:
+			break feed
+		}
+	}
+
+	close(words)
+	wg.Wait()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	elapsed := time.Since(start)
+
+	return &CrackResult{
+		Password:   password,
+		Found:      found,
+		Tried:      tried,
+		Elapsed:    elapsed,
+		Throughput: float64(tried) / elapsed.Seconds(),
+	}, nil
+}
+
+func CrackSimplifiedSRPFile(capture *SimplifiedSRPCapture, path string, workers int) (*CrackResult, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return CrackSimplifiedSRP(capture, f, workers)
+}
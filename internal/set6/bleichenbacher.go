@@ -0,0 +1,283 @@
+package set
+
+import (
+	"crypto/rand"
+	set5 "cryptopals/internal/set5"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// PadPKCS1v15 is the encryption padding 00 02 PS 00 M with at least 8 random
+// non-zero bytes PS, the result has the size k of the modulus.
+func PadPKCS1v15(message []byte, k int) ([]byte, error) {
+
+	if len(message) > k-11 {
+		return nil, errors.New("message too long")
+	}
+
+	block := make([]byte, k)
+	block[1] = 0x02
+
+	ps := block[2 : k-len(message)-1]
+	if _, err := rand.Read(ps); err != nil {
+		return nil, err
+	}
+
+	for i := range ps {
+		for ps[i] == 0 {
+			if _, err := rand.Read(ps[i : i+1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	copy(block[k-len(message):], message)
+
+	return block, nil
+}
+
+func UnpadPKCS1v15(block []byte) ([]byte, error) {
+
+	if len(block) < 11 || block[0] != 0x00 || block[1] != 0x02 {
+		return nil, errors.New("invalid padding")
+	}
+
+	for i := 2; i < len(block); i++ {
+		if block[i] == 0x00 {
+			if i < 10 {
+				break
+			}
+			return block[i+1:], nil
+		}
+	}
+
+	return nil, errors.New("invalid padding")
+}
+
+func EncryptPKCS1v15(pub *set5.PublicKey, message []byte) (*big.Int, error) {
+
+	block, err := PadPKCS1v15(message, modulusSize(pub))
+	if err != nil {
+		return nil, err
+	}
+
+	return pub.Encrypt(new(big.Int).SetBytes(block))
+}
+
+func DecryptPKCS1v15(priv *set5.PrivateKey, ciphertext *big.Int) ([]byte, error) {
+
+	m, err := priv.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnpadPKCS1v15(m.FillBytes(make([]byte, modulusSize(&priv.PublicKey))))
+}
+
+// PaddingOracleFactory returns an oracle that only checks whether the
+// plaintext starts with 00 02. It decrypts with the CRT since the attack
+// needs thousands of queries.
+func PaddingOracleFactory(priv *set5.PrivateKey) func(*big.Int) bool {
+
+	one := big.NewInt(1)
+	dp := new(big.Int).Mod(priv.D, new(big.Int).Sub(priv.P, one))
+	dq := new(big.Int).Mod(priv.D, new(big.Int).Sub(priv.Q, one))
+	qInv, err := set5.InvMod(priv.Q, priv.P)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid private key: %v", err))
+	}
+
+	// 00 02 means 2B <= m < 3B
+	k := modulusSize(&priv.PublicKey)
+	B := new(big.Int).Lsh(one, uint(8*(k-2)))
+	lower := new(big.Int).Lsh(B, 1)
+	upper := new(big.Int).Mul(B, big.NewInt(3))
+
+	return func(ciphertext *big.Int) bool {
+		if ciphertext.Sign() < 0 || ciphertext.Cmp(priv.N) >= 0 {
+			return false
+		}
+
+		mp := new(big.Int).Exp(ciphertext, dp, priv.P)
+		mq := new(big.Int).Exp(ciphertext, dq, priv.Q)
+
+		// m = mq + q * (qInv * (mp - mq) mod p)
+		h := mp.Sub(mp, mq)
+		h.Mul(h, qInv)
+		h.Mod(h, priv.P)
+
+		m := h.Mul(h, priv.Q)
+		m.Add(m, mq)
+
+		return m.Cmp(lower) >= 0 && m.Cmp(upper) < 0
+	}
+}
+
+type interval struct {
+	a, b *big.Int
+}
+
+// mergeIntervals sorts the intervals and joins the overlapping ones
+func mergeIntervals(intervals []interval) []interval {
+
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].a.Cmp(intervals[j].a) < 0 })
+
+	merged := []interval{}
+	for _, next := range intervals {
+		if n := len(merged); n > 0 && next.a.Cmp(merged[n-1].b) <= 0 {
+			if next.b.Cmp(merged[n-1].b) > 0 {
+				merged[n-1].b = next.b
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+
+	return merged
+}
+
+func floorDiv(a, b *big.Int) *big.Int {
+	return new(big.Int).Div(a, b)
+}
+
+func maxInt(a, b *big.Int) *big.Int {
+	if a.Cmp(b) > 0 {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+// BleichenbacherAttack recovers the padded plaintext block of a ciphertext
+// with a 00 02 padding oracle, following "Chosen Ciphertext Attacks Against
+// Protocols Based on the RSA Encryption Standard PKCS #1" (Bleichenbacher,
+// 1998). It returns the plaintext and the number of oracle queries.
+func BleichenbacherAttack(pub *set5.PublicKey, ciphertext *big.Int, oracle func(*big.Int) bool) (*big.Int, int, error) {
+
+	if _, err := pub.Encrypt(ciphertext); err != nil {
+		return nil, 0, err
+	}
+
+	n := pub.N
+	one := big.NewInt(1)
+	k := modulusSize(pub)
+
+	if k < 11 {
+		return nil, 0, errors.New("modulus too short for PKCS#1 v1.5")
+	}
+
+	B := new(big.Int).Lsh(one, uint(8*(k-2)))
+	B2 := new(big.Int).Lsh(B, 1)
+	B3 := new(big.Int).Mul(B, big.NewInt(3))
+	B3minus1 := new(big.Int).Sub(B3, one)
+
+	queries := 0
+
+	// conforming reports whether c0 * s^e is PKCS conforming
+	conforming := func(c0, s *big.Int) bool {
+		queries++
+		c := set5.ModExp(s, pub.E, n)
+		c.Mul(c, c0)
+		return oracle(c.Mod(c, n))
+	}
+
+	// step 1: blinding, which is skipped for conforming ciphertexts
+	s0 := big.NewInt(1)
+	for !conforming(ciphertext, s0) {
+		var err error
+		if s0, err = rand.Int(rand.Reader, n); err != nil {
+			return nil, queries, err
+		}
+	}
+
+	c0 := set5.ModExp(s0, pub.E, n)
+	c0.Mul(c0, ciphertext)
+	c0.Mod(c0, n)
+
+	M := []interval{{a: new(big.Int).Set(B2), b: new(big.Int).Set(B3minus1)}}
+	var s *big.Int
+
+	for i := 1; ; i++ {
+		switch {
+		case i == 1:
+			// step 2a: smallest s >= n / 3B
+			s = ceilDiv(n, B3)
+			for !conforming(c0, s) {
+				s.Add(s, one)
+			}
+		case len(M) > 1:
+			// step 2b: linear search from the last s
+			s = new(big.Int).Add(s, one)
+			for !conforming(c0, s) {
+				s.Add(s, one)
+			}
+		default:
+			// step 2c: r >= 2 (b*s - 2B) / n roughly doubles s every round
+			a, b := M[0].a, M[0].b
+
+			r := new(big.Int).Mul(b, s)
+			r.Sub(r, B2)
+			r = ceilDiv(r.Lsh(r, 1), n)
+
+		search:
+			for ; ; r.Add(r, one) {
+				rn := new(big.Int).Mul(r, n)
+				from := ceilDiv(new(big.Int).Add(B2, rn), b)
+				to := floorDiv(new(big.Int).Add(B3minus1, rn), a)
+
+				for candidate := from; candidate.Cmp(to) <= 0; candidate.Add(candidate, one) {
+					if conforming(c0, candidate) {
+						s = candidate
+						break search
+					}
+				}
+			}
+		}
+
+		// step 3: narrow every interval with the new s
+		var next []interval
+		for _, m := range M {
+			from := new(big.Int).Mul(m.a, s)
+			from.Sub(from, B3minus1)
+			from = ceilDiv(from, n)
+
+			to := new(big.Int).Mul(m.b, s)
+			to = floorDiv(to.Sub(to, B2), n)
+
+			for r := from; r.Cmp(to) <= 0; r = new(big.Int).Add(r, one) {
+				rn := new(big.Int).Mul(r, n)
+				a := maxInt(m.a, ceilDiv(new(big.Int).Add(B2, rn), s))
+				b := minInt(m.b, floorDiv(new(big.Int).Add(B3minus1, rn), s))
+
+				if a.Cmp(b) <= 0 {
+					next = append(next, interval{a: a, b: b})
+				}
+			}
+		}
+
+		if len(next) == 0 {
+			return nil, queries, errors.New("no interval left, the oracle is inconsistent")
+		}
+
+		M = mergeIntervals(next)
+
+		// step 4: a single value is left
+		if len(M) == 1 && M[0].a.Cmp(M[0].b) == 0 {
+			m, err := set5.InvMod(s0, n)
+			if err != nil {
+				return nil, queries, err
+			}
+
+			m.Mul(m, M[0].a)
+			return m.Mod(m, n), queries, nil
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package set
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	set5 "cryptopals/internal/set5"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+)
+
+type DSAParams struct {
+	P, Q, G *big.Int
+}
+
+func mustHex(s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic(fmt.Sprintf("Invalid hex constant %q", s))
+	}
+	return x
+}
+
+// DefaultDSAParams are the parameters given in challenge 43
+func DefaultDSAParams() *DSAParams {
+	return &DSAParams{
+		P: mustHex("800000000000000089e1855218a0e7dac38136ffafa72eda7" +
+			"859f2171e25e65eac698c1702578b07dc2a1076da241c76c6" +
+			"2d374d8389ea5aeffd3226a0530cc565f3bf6b50929139ebe" +
+			"ac04f48c3c84afb796d61e5a4f9a8fda812ab59494232c7d2" +
+			"b4deb50aa18ee9e132bfa85ac4374d7f9091abc3d015efc87" +
+			"1a584471bb1"),
+		Q: mustHex("f4f47f05794b256174bba6e9b396a7707e563c5b"),
+		G: mustHex("5958c9d3898b224b12672c0b98e06c60df923cb8bc999d119" +
+			"458fef538b8fa4046c8db53039db620c094c9fa077ef389b5" +
+			"322a559946a71903f990f1f7e0e025e2d7f7cf494aff1a047" +
+			"0f5b64c36b625a097f1651fe775323556fe00b3608c887892" +
+			"878480e99041be601a62166ca6894bdd41a7054ec89f756ba" +
+			"9fc95302291"),
+	}
+}
+
+type DSAPublicKey struct {
+	Params *DSAParams
+	Y      *big.Int
+}
+
+type DSAPrivateKey struct {
+	DSAPublicKey
+	X *big.Int
+}
+
+type DSASignature struct {
+	R, S *big.Int
+}
+
+// DSAHash is SHA-1 of the message as integer, it fits q without truncation
+func DSAHash(message []byte) *big.Int {
+	digest := sha1.Sum(message)
+	return new(big.Int).SetBytes(digest[:])
+}
+
+// randomScalar returns a uniform value in [1, q-1]
+func randomScalar(q *big.Int) (*big.Int, error) {
+	x, err := rand.Int(rand.Reader, new(big.Int).Sub(q, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	return x.Add(x, big.NewInt(1)), nil
+}
+
+func NewDSAPrivateKey(params *DSAParams, x *big.Int) *DSAPrivateKey {
+	return &DSAPrivateKey{
+		DSAPublicKey: DSAPublicKey{Params: params, Y: new(big.Int).Exp(params.G, x, params.P)},
+		X:            x,
+	}
+}
+
+func GenerateDSAKey(params *DSAParams) (*DSAPrivateKey, error) {
+
+	x, err := randomScalar(params.Q)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDSAPrivateKey(params, x), nil
+}
+
+// SignWithNonce signs the hash h with the given k, which is only safe if k is
+// random, secret and never reused. It fails if r or s is zero.
+func (priv *DSAPrivateKey) SignWithNonce(h, k *big.Int) (*DSASignature, error) {
+
+	p, q, g := priv.Params.P, priv.Params.Q, priv.Params.G
+
+	// r = (g^k mod p) mod q
+	r := new(big.Int).Exp(g, k, p)
+	r.Mod(r, q)
+
+	kInv, err := set5.InvMod(k, q)
+	if r.Sign() == 0 || err != nil {
+		return nil, errors.New("bad nonce")
+	}
+
+	// s = k^-1 (H(m) + xr) mod q
+	s := new(big.Int).Mul(priv.X, r)
+	s.Add(s, h)
+	s.Mul(s, kInv)
+	s.Mod(s, q)
+
+	if s.Sign() == 0 {
+		return nil, errors.New("bad nonce")
+	}
+
+	return &DSASignature{R: r, S: s}, nil
+}
+
+func (priv *DSAPrivateKey) Sign(message []byte) (*DSASignature, error) {
+
+	h := DSAHash(message)
+
+	for {
+		k, err := randomScalar(priv.Params.Q)
+		if err != nil {
+			return nil, err
+		}
+
+		if sig, err := priv.SignWithNonce(h, k); err == nil {
+			return sig, nil
+		}
+	}
+}
+
+// VerifyHash checks the signature of the hash h
+func (pub *DSAPublicKey) VerifyHash(h *big.Int, sig *DSASignature) bool {
+
+	p, q, g := pub.Params.P, pub.Params.Q, pub.Params.G
+
+	if sig.R.Sign() <= 0 || sig.R.Cmp(q) >= 0 || sig.S.Sign() <= 0 || sig.S.Cmp(q) >= 0 {
+		return false
+	}
+
+	w, err := set5.InvMod(sig.S, q)
+	if err != nil {
+		return false
+	}
+
+	u1 := new(big.Int).Mul(h, w)
+	u1.Mod(u1, q)
+
+	u2 := new(big.Int).Mul(sig.R, w)
+	u2.Mod(u2, q)
+
+	// v = (g^u1 * y^u2 mod p) mod q
+	v := new(big.Int).Exp(g, u1, p)
+	v.Mul(v, new(big.Int).Exp(pub.Y, u2, p))
+	v.Mod(v, p)
+	v.Mod(v, q)
+
+	return v.Cmp(sig.R) == 0
+}
+
+func (pub *DSAPublicKey) Verify(message []byte, sig *DSASignature) bool {
+	return pub.VerifyHash(DSAHash(message), sig)
+}
+
+// RecoverDSAKey computes x = (s * k - H(m)) / r mod q from a known nonce
+func RecoverDSAKey(params *DSAParams, h *big.Int, sig *DSASignature, k *big.Int) (*big.Int, error) {
+
+	q := params.Q
+
+	rInv, err := set5.InvMod(sig.R, q)
+	if err != nil {
+		return nil, err
+	}
+
+	x := new(big.Int).Mul(sig.S, k)
+	x.Sub(x, h)
+	x.Mul(x, rInv)
+
+	return x.Mod(x, q), nil
+}
+
+// BruteForceDSANonce tries every k in [1, bound) and returns the private key
+// whose public key matches. g^k is updated with one multiplication per guess.
+func BruteForceDSANonce(pub *DSAPublicKey, h *big.Int, sig *DSASignature, bound int64) (*DSAPrivateKey, error) {
+
+	p, q, g := pub.Params.P, pub.Params.Q, pub.Params.G
+
+	gk := new(big.Int).Set(g)
+	r := new(big.Int)
+
+	for k := int64(1); k < bound; k++ {
+		if r.Mod(gk, q).Cmp(sig.R) == 0 {
+			x, err := RecoverDSAKey(pub.Params, h, sig, big.NewInt(k))
+			if err == nil && new(big.Int).Exp(g, x, p).Cmp(pub.Y) == 0 {
+				return NewDSAPrivateKey(pub.Params, x), nil
+			}
+		}
+
+		gk.Mul(gk, g)
+		gk.Mod(gk, p)
+	}
+
+	return nil, errors.New("nonce not found")
+}
+
+// SignedMessage is one entry of the 44.txt format
+type SignedMessage struct {
+	Message []byte
+	Hash    *big.Int
+	DSASignature
+}
+
+// ParseSignedMessages reads blocks of "msg: ", "s: ", "r: " and "m: " lines,
+// s and r are decimal and m is the hex SHA-1 of the message.
+func ParseSignedMessages(input io.Reader) ([]SignedMessage, error) {
+
+	var (
+		messages []SignedMessage
+		current  SignedMessage
+		fields   int
+		line     int
+	)
+
+	scanner := bufio.NewScanner(input)
+	scanner.Split(bufio.ScanLines)
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(text, ": ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected a key and a value", line)
+		}
+
+		key, value := parts[0], parts[1]
+
+		var valid bool
+		switch {
+		case key == "msg" && fields == 0:
+			current.Message, valid = []byte(value), true
+		case key == "s" && fields == 1:
+			current.S, valid = new(big.Int).SetString(strings.TrimSpace(value), 10)
+		case key == "r" && fields == 2:
+			current.R, valid = new(big.Int).SetString(strings.TrimSpace(value), 10)
+		case key == "m" && fields == 3:
+			current.Hash, valid = new(big.Int).SetString(strings.TrimSpace(value), 16)
+		default:
+			return nil, fmt.Errorf("line %d: unexpected key %q", line, key)
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("line %d: invalid value for %q", line, key)
+		}
+
+		fields++
+		if fields == 4 {
+			messages = append(messages, current)
+			current, fields = SignedMessage{}, 0
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if fields != 0 {
+		return nil, errors.New("truncated entry at the end of input")
+	}
+
+	return messages, nil
+}
+
+func ParseSignedMessagesFile(path string) ([]SignedMessage, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return ParseSignedMessages(f)
+}
+
+// RecoverKeyFromRepeatedNonce looks for two signatures with the same r, which
+// share k = (m1 - m2) / (s1 - s2) mod q, and returns the key matching pub.
+func RecoverKeyFromRepeatedNonce(pub *DSAPublicKey, messages []SignedMessage) (*DSAPrivateKey, error) {
+
+	q := pub.Params.Q
+
+	for i := range messages {
+		for j := i + 1; j < len(messages); j++ {
+			a, b := &messages[i], &messages[j]
+			if a.R.Cmp(b.R) != 0 {
+				continue
+			}
+
+			ds := new(big.Int).Sub(a.S, b.S)
+			dsInv, err := set5.InvMod(ds, q)
+			if err != nil {
+				continue
+			}
+
+			k := new(big.Int).Sub(a.Hash, b.Hash)
+			k.Mul(k, dsInv)
+			k.Mod(k, q)
+
+			x, err := RecoverDSAKey(pub.Params, a.Hash, &a.DSASignature, k)
+			if err == nil && new(big.Int).Exp(pub.Params.G, x, pub.Params.P).Cmp(pub.Y) == 0 {
+				return NewDSAPrivateKey(pub.Params, x), nil
+			}
+		}
+	}
+
+	return nil, errors.New("no repeated nonce found")
+}
@@ -0,0 +1,122 @@
+package set
+
+import (
+	set5 "cryptopals/internal/set5"
+	"errors"
+	"math/big"
+)
+
+type ParamsMode int
+
+const (
+	// Hardened also rejects degenerate generators and r = 0
+	Hardened ParamsMode = iota
+	// Trusting uses whatever generator it is given
+	Trusting
+)
+
+// DSAVerifier checks signatures under domain parameters chosen by the caller,
+// which is attacker controlled in challenge 45.
+type DSAVerifier struct {
+	Params *DSAParams
+	Mode   ParamsMode
+}
+
+// computable rejects what would crash or never finish in both modes: moduli
+// below 2, s outside [1, q) and r outside [0, q). r = 0 is left to Hardened
+// since it is exactly what the g = 0 attack needs.
+func computable(params *DSAParams, sig *DSASignature) bool {
+
+	one := big.NewInt(1)
+
+	if params.P.Cmp(one) <= 0 || params.Q.Cmp(one) <= 0 {
+		return false
+	}
+
+	return sig.S.Sign() > 0 && sig.S.Cmp(params.Q) < 0 && sig.R.Sign() >= 0 && sig.R.Cmp(params.Q) < 0
+}
+
+// validGenerator requires 1 < g < p and g of order q
+func validGenerator(params *DSAParams) bool {
+
+	one := big.NewInt(1)
+
+	if params.G.Cmp(one) <= 0 || params.G.Cmp(params.P) >= 0 {
+		return false
+	}
+
+	return new(big.Int).Exp(params.G, params.Q, params.P).Cmp(one) == 0
+}
+
+func (v *DSAVerifier) Verify(y *big.Int, message []byte, sig *DSASignature) bool {
+
+	if !computable(v.Params, sig) {
+		return false
+	}
+
+	if v.Mode == Hardened {
+		pub := &DSAPublicKey{Params: v.Params, Y: y}
+		return validGenerator(v.Params) && pub.Verify(message, sig)
+	}
+
+	p, q, g := v.Params.P, v.Params.Q, v.Params.G
+
+	w, err := set5.InvMod(sig.S, q)
+	if err != nil {
+		return false
+	}
+
+	u1 := new(big.Int).Mul(DSAHash(message), w)
+	u1.Mod(u1, q)
+
+	u2 := new(big.Int).Mul(sig.R, w)
+	u2.Mod(u2, q)
+
+	r := new(big.Int).Exp(g, u1, p)
+	r.Mul(r, new(big.Int).Exp(y, u2, p))
+	r.Mod(r, p)
+	r.Mod(r, q)
+
+	return r.Cmp(sig.R) == 0
+}
+
+// MagicSignature returns a signature that verifies for any message under the
+// tampered generator of params:
+//
+//	g = 0:		r = 0 since 0^u1 = 0, s is arbitrary
+//	g = p+1:	r = (y^z mod p) mod q, s = r / z mod q since g^u1 = 1
+func MagicSignature(params *DSAParams, y *big.Int) (*DSASignature, error) {
+
+	p, q := params.P, params.Q
+
+	if p.Cmp(big.NewInt(1)) <= 0 || q.Cmp(big.NewInt(1)) <= 0 {
+		return nil, errors.New("degenerate modulus")
+	}
+
+	g := new(big.Int).Mod(params.G, p)
+
+	z, err := randomScalar(q)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case g.Sign() == 0:
+		return &DSASignature{R: big.NewInt(0), S: z}, nil
+	case g.Cmp(big.NewInt(1)) == 0:
+		r := new(big.Int).Exp(y, z, p)
+		r.Mod(r, q)
+
+		s, err := set5.InvMod(z, q)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Mul(s, r)
+		s.Mod(s, q)
+
+		return &DSASignature{R: r, S: s}, nil
+	}
+
+	return nil, errors.New("no magic signature for this generator")
+}
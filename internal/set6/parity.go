@@ -0,0 +1,63 @@
+package set
+
+import (
+	set5 "cryptopals/internal/set5"
+	"math/big"
+)
+
+// ParityOracleFactory returns an oracle that decrypts the ciphertext and only
+// tells whether the plaintext is even.
+func ParityOracleFactory(key *set5.PrivateKey) func(*big.Int) bool {
+	return func(ciphertext *big.Int) bool {
+		plaintext, err := key.Decrypt(ciphertext)
+		return err == nil && plaintext.Bit(0) == 0
+	}
+}
+
+// ceilDiv returns ceil(a / b) for non-negative a and positive b
+func ceilDiv(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// ParityAttack multiplies the plaintext by 2 with every query. Since N is odd,
+// 2P mod N is even iff 2P < N, which halves the interval of the plaintext.
+// After i queries P lies in [N * k / 2^i, N * (k+1) / 2^i), the bounds are
+// computed exactly from k and reported as integers after every query.
+func ParityAttack(pub *set5.PublicKey, ciphertext *big.Int, isEven func(*big.Int) bool, progress func(lower, upper *big.Int)) (*big.Int, error) {
+
+	if _, err := pub.Encrypt(ciphertext); err != nil {
+		return nil, err
+	}
+
+	double := set5.ModExp(big.NewInt(2), pub.E, pub.N)
+	c := new(big.Int).Set(ciphertext)
+	k := new(big.Int)
+	denominator := big.NewInt(1)
+
+	lower := new(big.Int)
+	for i := 0; i < pub.N.BitLen(); i++ {
+		c.Mul(c, double)
+		c.Mod(c, pub.N)
+
+		k.Lsh(k, 1)
+		denominator.Lsh(denominator, 1)
+
+		if !isEven(c) {
+			k.Add(k, big.NewInt(1))
+		}
+
+		lower = ceilDiv(new(big.Int).Mul(pub.N, k), denominator)
+
+		if progress != nil {
+			upper := ceilDiv(new(big.Int).Mul(pub.N, new(big.Int).Add(k, big.NewInt(1))), denominator)
+			progress(lower, upper.Sub(upper, big.NewInt(1)))
+		}
+	}
+
+	// the interval is now narrower than 1
+	return lower, nil
+}
@@ -0,0 +1,162 @@
+package set
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	set5 "cryptopals/internal/set5"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// DER encoded DigestInfo without the digest itself (RFC 8017, section 9.2)
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+}
+
+func digestInfo(h crypto.Hash, message []byte) ([]byte, error) {
+
+	prefix, ok := digestInfoPrefixes[h]
+	if !ok || !h.Available() {
+		return nil, fmt.Errorf("unsupported hash %v", h)
+	}
+
+	d := h.New()
+	d.Write(message)
+
+	return d.Sum(append([]byte{}, prefix...)), nil
+}
+
+func modulusSize(pub *set5.PublicKey) int {
+	return (pub.N.BitLen() + 7) / 8
+}
+
+// EncodeSignature is EMSA-PKCS1-v1_5: 00 01 FF .. FF 00 DigestInfo of k bytes
+func EncodeSignature(h crypto.Hash, message []byte, k int) ([]byte, error) {
+
+	info, err := digestInfo(h, message)
+	if err != nil {
+		return nil, err
+	}
+
+	// at least 8 bytes of padding
+	if k < len(info)+11 {
+		return nil, errors.New("modulus too short for the digest")
+	}
+
+	encoded := append([]byte{0x00, 0x01}, bytes.Repeat([]byte{0xff}, k-len(info)-3)...)
+	encoded = append(encoded, 0x00)
+
+	return append(encoded, info...), nil
+}
+
+func SignPKCS1v15(priv *set5.PrivateKey, h crypto.Hash, message []byte) ([]byte, error) {
+
+	k := modulusSize(&priv.PublicKey)
+	encoded, err := EncodeSignature(h, message, k)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := priv.Sign(new(big.Int).SetBytes(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	return signature.FillBytes(make([]byte, k)), nil
+}
+
+// openSignature computes s^e and left pads it to the size of the modulus
+func openSignature(pub *set5.PublicKey, signature []byte) ([]byte, bool) {
+
+	k := modulusSize(pub)
+	s := new(big.Int).SetBytes(signature)
+
+	if len(signature) != k || s.Cmp(pub.N) >= 0 {
+		return nil, false
+	}
+
+	return set5.ModExp(s, pub.E, pub.N).FillBytes(make([]byte, k)), true
+}
+
+// VerifySloppy parses 00 01 FF .. FF 00 DigestInfo from the left like a broken
+// implementation: it neither checks the padding length nor that the digest
+// ends the block, so anything can follow it.
+func VerifySloppy(pub *set5.PublicKey, h crypto.Hash, message, signature []byte) bool {
+
+	block, ok := openSignature(pub, signature)
+	if !ok || !bytes.HasPrefix(block, []byte{0x00, 0x01}) {
+		return false
+	}
+
+	i := 2
+	for i < len(block) && block[i] == 0xff {
+		i++
+	}
+
+	if i == 2 || i == len(block) || block[i] != 0x00 {
+		return false
+	}
+
+	info, err := digestInfo(h, message)
+	if err != nil {
+		return false
+	}
+
+	return bytes.HasPrefix(block[i+1:], info)
+}
+
+// VerifyStrict encodes the expected block and compares it to the whole block
+func VerifyStrict(pub *set5.PublicKey, h crypto.Hash, message, signature []byte) bool {
+
+	block, ok := openSignature(pub, signature)
+	if !ok {
+		return false
+	}
+
+	expected, err := EncodeSignature(h, message, len(block))
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(block, expected)
+}
+
+// ForgeSignature is Bleichenbacher's e=3 attack: 00 01 FF 00 DigestInfo is
+// followed by garbage, which gives enough room such that the cube of the
+// floored cube root of the block filled with FF still starts with the prefix.
+func ForgeSignature(pub *set5.PublicKey, h crypto.Hash, message []byte) ([]byte, error) {
+
+	info, err := digestInfo(h, message)
+	if err != nil {
+		return nil, err
+	}
+
+	if pub.E.Sign() <= 0 {
+		return nil, fmt.Errorf("exponent %s must be positive", pub.E)
+	}
+
+	if !pub.E.IsInt64() {
+		return nil, errors.New("exponent too large")
+	}
+
+	k := modulusSize(pub)
+	prefix := append([]byte{0x00, 0x01, 0xff, 0x00}, info...)
+
+	if len(prefix) >= k {
+		return nil, errors.New("modulus too short for the digest")
+	}
+
+	target := append(prefix, bytes.Repeat([]byte{0xff}, k-len(prefix))...)
+	root, _ := set5.NthRoot(new(big.Int).SetBytes(target), int(pub.E.Int64()))
+
+	forged := new(big.Int).Exp(root, pub.E, nil)
+	if forged.Cmp(pub.N) >= 0 || !bytes.HasPrefix(forged.FillBytes(make([]byte, k)), prefix) {
+		return nil, errors.New("not enough garbage bytes to hide the error of the root")
+	}
+
+	return root.FillBytes(make([]byte, k)), nil
+}
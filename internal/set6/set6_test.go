@@ -0,0 +1,499 @@
+package set_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	set5 "cryptopals/internal/set5"
+	set "cryptopals/internal/set6"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverUnpaddedMessage(t *testing.T) {
+
+	key, err := set5.GenerateKey(1024, big.NewInt(65537))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(set.CreateRSADecryptionHandler(key))
+	defer server.Close()
+	client := server.Client()
+
+	message := new(big.Int).SetBytes([]byte(`{time: 1356304276, social: '555-55-5555'}`))
+	ciphertext, err := key.Encrypt(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the legitimate owner decrypts it first
+	if plaintext, err := set.RequestDecryption(client, server.URL, ciphertext); err != nil || plaintext.Cmp(message) != 0 {
+		t.Fatalf("Expected the message, got %s (%v)", plaintext, err)
+	}
+
+	if _, err := set.RequestDecryption(client, server.URL, ciphertext); err == nil {
+		t.Fatal("Server decrypted the same ciphertext twice")
+	}
+
+	recovered, err := set.RecoverUnpaddedMessage(client, server.URL, &key.PublicKey, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recovered.Cmp(message) != 0 {
+		t.Fatalf("Expected %s, got %s", message.Bytes(), recovered.Bytes())
+	}
+}
+
+func TestPKCS1v15Signature(t *testing.T) {
+
+	key, err := set5.GenerateKey(1024, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("hi mom")
+
+	for _, h := range []crypto.Hash{crypto.SHA1, crypto.SHA256} {
+		signature, err := set.SignPKCS1v15(key, h, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !set.VerifyStrict(&key.PublicKey, h, message, signature) || !set.VerifySloppy(&key.PublicKey, h, message, signature) {
+			t.Errorf("%v: valid signature rejected", h)
+		}
+
+		if set.VerifyStrict(&key.PublicKey, h, []byte("hi dad"), signature) || set.VerifySloppy(&key.PublicKey, h, []byte("hi dad"), signature) {
+			t.Errorf("%v: signature accepted for another message", h)
+		}
+	}
+}
+
+func TestForgeSignature(t *testing.T) {
+
+	tests := []struct {
+		hash crypto.Hash
+		bits int
+	}{
+		{hash: crypto.SHA1, bits: 1024},
+		{hash: crypto.SHA256, bits: 2048},
+	}
+
+	for _, tt := range tests {
+		key, err := set5.GenerateKey(tt.bits, big.NewInt(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		message := []byte("hi mom")
+
+		forged, err := set.ForgeSignature(&key.PublicKey, tt.hash, message)
+		if err != nil {
+			t.Fatalf("%v with %d bits: %v", tt.hash, tt.bits, err)
+		}
+
+		if !set.VerifySloppy(&key.PublicKey, tt.hash, message, forged) {
+			t.Errorf("%v with %d bits: forgery rejected by the sloppy verifier", tt.hash, tt.bits)
+		}
+
+		if set.VerifyStrict(&key.PublicKey, tt.hash, message, forged) {
+			t.Errorf("%v with %d bits: forgery accepted by the strict verifier", tt.hash, tt.bits)
+		}
+	}
+
+	for _, e := range []int64{0, -3} {
+		pub := &set5.PublicKey{N: big.NewInt(3233), E: big.NewInt(e)}
+		if _, err := set.ForgeSignature(pub, crypto.SHA1, []byte("hi mom")); err == nil {
+			t.Errorf("Expected error for e = %d", e)
+		}
+	}
+}
+
+func TestDSA(t *testing.T) {
+
+	key, err := set.GenerateDSAKey(set.DefaultDSAParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("hi mom")
+
+	sig, err := key.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !key.Verify(message, sig) {
+		t.Error("Valid signature rejected")
+	}
+
+	if key.Verify([]byte("hi dad"), sig) {
+		t.Error("Signature accepted for another message")
+	}
+
+	tampered := &set.DSASignature{R: sig.R, S: new(big.Int).Add(sig.S, big.NewInt(1))}
+	if key.Verify(message, tampered) {
+		t.Error("Tampered signature accepted")
+	}
+}
+
+func TestRecoverDSAKey(t *testing.T) {
+
+	key, err := set.GenerateDSAKey(set.DefaultDSAParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := set.DSAHash([]byte("hi mom"))
+	k := big.NewInt(31337)
+
+	sig, err := key.SignWithNonce(h, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := set.RecoverDSAKey(key.Params, h, sig, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x.Cmp(key.X) != 0 {
+		t.Fatalf("Expected %x, got %x", key.X, x)
+	}
+}
+
+func TestBruteForceDSANonce(t *testing.T) {
+
+	y, _ := new(big.Int).SetString("84ad4719d044495496a3201c8ff484feb45b962e7302e56a392aee4a"+
+		"bab3e4bdebf2955b4736012f21a08084056b19bcd7fee56048e004"+
+		"e44984e2f411788efdc837a0d2e5abb7b555039fd243ac01f0fb2ed"+
+		"1dec568280ce678e931868d23eb095fde9d3779191b8c0299d6e07b"+
+		"bb283e6633451e535c45513b2d33c99ea17", 16)
+
+	r, _ := new(big.Int).SetString("548099063082341131477253921760299949438196259240", 10)
+	s, _ := new(big.Int).SetString("857042759984254168557880549501802188789837994940", 10)
+
+	message := []byte("For those that envy a MC it can be hazardous to your health\n" +
+		"So be friendly, a matter of life and death, just like a etch-a-sketch\n")
+
+	h := set.DSAHash(message)
+	if h.Text(16) != "d2d0714f014a9784047eaeccf956520045c45265" {
+		t.Fatalf("Unexpected hash %x", h)
+	}
+
+	pub := &set.DSAPublicKey{Params: set.DefaultDSAParams(), Y: y}
+	sig := &set.DSASignature{R: r, S: s}
+
+	if !pub.VerifyHash(h, sig) {
+		t.Fatal("Challenge signature does not verify")
+	}
+
+	key, err := set.BruteForceDSANonce(pub, h, sig, 1<<16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fingerprint := sha1.Sum([]byte(key.X.Text(16))); hex.EncodeToString(fingerprint[:]) != "0954edd5e0afe5542a4adf012611a91912a3ec16" {
+		t.Fatalf("Unexpected fingerprint %x of %x", fingerprint, key.X)
+	}
+}
+
+func TestRecoverKeyFromRepeatedNonce(t *testing.T) {
+
+	messages, err := set.ParseSignedMessagesFile("testdata/set6-ch44.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 11 {
+		t.Fatalf("Expected 11 messages, got %d", len(messages))
+	}
+
+	// the trailing space is part of the message
+	if string(messages[0].Message) != "Listen for me, you better listen for me now. " {
+		t.Fatalf("Unexpected first message %q", messages[0].Message)
+	}
+
+	y, _ := new(big.Int).SetString("2d026f4bf30195ede3a088da85e398ef869611d0f68f0713d51c9c1a3a26c95105"+
+		"d915e2d8cdf26d056b86b8a7b85519b1c23cc3ecdc6062650462e3063bd179c2a658"+
+		"1519f674a61f1d89a1fff27171ebc1b93d4dc57bceb7ae2430f98a6a4d83d8279ee6"+
+		"5d71c1203d2c96d65ebbf7cce9d32971c3de5084cce04a2e147821", 16)
+
+	pub := &set.DSAPublicKey{Params: set.DefaultDSAParams(), Y: y}
+
+	for _, m := range messages {
+		if !pub.VerifyHash(m.Hash, &m.DSASignature) {
+			t.Fatalf("Invalid signature for %q", m.Message)
+		}
+	}
+
+	key, err := set.RecoverKeyFromRepeatedNonce(pub, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fingerprint := sha1.Sum([]byte(key.X.Text(16))); hex.EncodeToString(fingerprint[:]) != "ca8f6f7c66fa362d40760d135b763eb8527d3d52" {
+		t.Fatalf("Unexpected fingerprint %x of %x", fingerprint, key.X)
+	}
+
+	if _, err := set.RecoverKeyFromRepeatedNonce(pub, messages[:8]); err == nil {
+		t.Fatal("Recovered a key without repeated nonces")
+	}
+}
+
+func TestParseSignedMessages(t *testing.T) {
+
+	input := "msg: Listen for me, you better listen for me now. \n" +
+		"s: 1267396447369736888040262262183731677867615804316\n" +
+		"r: 1105520928110492191417703162650245113664610474875\n" +
+		"m: a4db3de27e2db3e5ef085ced2bced91b82e0df19\n"
+
+	messages, err := set.ParseSignedMessages(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 || string(messages[0].Message) != "Listen for me, you better listen for me now. " {
+		t.Fatalf("Unexpected messages %v", messages)
+	}
+
+	if _, err := set.ParseSignedMessages(strings.NewReader(input[:len(input)-47])); err == nil {
+		t.Fatal("Accepted a truncated entry")
+	}
+
+	if _, err := set.ParseSignedMessages(strings.NewReader("r: 1\n")); err == nil {
+		t.Fatal("Accepted an entry out of order")
+	}
+}
+
+func TestMagicSignature(t *testing.T) {
+
+	key, err := set.GenerateDSAKey(set.DefaultDSAParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := key.Params.P
+
+	tests := []struct {
+		name string
+		g    *big.Int
+	}{
+		{name: "g = 0", g: big.NewInt(0)},
+		{name: "g = p+1", g: new(big.Int).Add(p, big.NewInt(1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &set.DSAParams{P: p, Q: key.Params.Q, G: tt.g}
+
+			sig, err := set.MagicSignature(params, key.Y)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			trusting := &set.DSAVerifier{Params: params, Mode: set.Trusting}
+			hardened := &set.DSAVerifier{Params: params, Mode: set.Hardened}
+
+			for _, message := range []string{"Hello, world", "Goodbye, world"} {
+				if !trusting.Verify(key.Y, []byte(message), sig) {
+					t.Errorf("Trusting verifier rejected %q", message)
+				}
+
+				if hardened.Verify(key.Y, []byte(message), sig) {
+					t.Errorf("Hardened verifier accepted %q", message)
+				}
+			}
+		})
+	}
+
+	// both modes agree on honest parameters
+	message := []byte("Hello, world")
+	sig, err := key.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []set.ParamsMode{set.Trusting, set.Hardened} {
+		if !(&set.DSAVerifier{Params: key.Params, Mode: mode}).Verify(key.Y, message, sig) {
+			t.Errorf("Mode %d rejected a valid signature", mode)
+		}
+	}
+
+	if _, err := set.MagicSignature(key.Params, key.Y); err == nil {
+		t.Error("Magic signature for an honest generator")
+	}
+}
+
+func TestDSAVerifierDegenerateParams(t *testing.T) {
+
+	key, err := set.GenerateDSAKey(set.DefaultDSAParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("Hello, world")
+	sig, err := key.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, q, g := key.Params.P, key.Params.Q, key.Params.G
+
+	tests := []struct {
+		name   string
+		params *set.DSAParams
+		sig    *set.DSASignature
+	}{
+		{name: "q = 0", params: &set.DSAParams{P: p, Q: big.NewInt(0), G: g}, sig: sig},
+		{name: "q = 1", params: &set.DSAParams{P: p, Q: big.NewInt(1), G: g}, sig: sig},
+		{name: "p = 0", params: &set.DSAParams{P: big.NewInt(0), Q: q, G: g}, sig: sig},
+		{name: "p = 1", params: &set.DSAParams{P: big.NewInt(1), Q: q, G: g}, sig: sig},
+		{name: "s = 0", params: key.Params, sig: &set.DSASignature{R: sig.R, S: big.NewInt(0)}},
+		{name: "s = q", params: key.Params, sig: &set.DSASignature{R: sig.R, S: q}},
+		{name: "r = -1", params: key.Params, sig: &set.DSASignature{R: big.NewInt(-1), S: sig.S}},
+		{name: "r = q", params: key.Params, sig: &set.DSASignature{R: q, S: sig.S}},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []set.ParamsMode{set.Trusting, set.Hardened} {
+			if (&set.DSAVerifier{Params: tt.params, Mode: mode}).Verify(key.Y, message, tt.sig) {
+				t.Errorf("%s: mode %d accepted the signature", tt.name, mode)
+			}
+		}
+	}
+}
+
+func TestParityAttack(t *testing.T) {
+
+	message, err := base64.StdEncoding.DecodeString("VGhhdCdzIHdoeSBJIGZvdW5kIHlvdSBkb24ndCBwbGF5IGFyb3VuZCB3aXRoIHRoZSBGdW5reSBDb2xkIE1lZGluYQ==")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bits := range []int{1024, 2048} {
+		if bits > 1024 && testing.Short() {
+			continue
+		}
+
+		key, err := set5.GenerateKey(bits, big.NewInt(65537))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := new(big.Int).SetBytes(message)
+		ciphertext, err := key.Encrypt(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var steps int
+		lastWidth := new(big.Int).Set(key.N)
+
+		progress := func(lower, upper *big.Int) {
+			steps++
+			width := new(big.Int).Sub(upper, lower)
+			if lower.Cmp(m) > 0 || upper.Cmp(m) < 0 || width.Cmp(lastWidth) > 0 {
+				t.Fatalf("%d bits, step %d: [%x, %x] does not narrow down to the message", bits, steps, lower, upper)
+			}
+			lastWidth = width
+		}
+
+		recovered, err := set.ParityAttack(&key.PublicKey, ciphertext, set.ParityOracleFactory(key), progress)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(recovered.Bytes(), message) {
+			t.Fatalf("%d bits: expected %q, got %q", bits, message, recovered.Bytes())
+		}
+
+		if steps != key.N.BitLen() || lastWidth.Sign() != 0 {
+			t.Fatalf("%d bits: %d steps ended with width %s", bits, steps, lastWidth)
+		}
+	}
+}
+
+func TestPKCS1v15Encryption(t *testing.T) {
+
+	key, err := set5.GenerateKey(512, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("kick it, CC")
+
+	ciphertext, err := set.EncryptPKCS1v15(&key.PublicKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := set.DecryptPKCS1v15(key, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decrypted, message) {
+		t.Fatalf("Expected %q, got %q", message, decrypted)
+	}
+
+	if _, err := set.PadPKCS1v15(make([]byte, 54), 64); err == nil {
+		t.Error("Padded a message without room for 8 bytes of padding")
+	}
+
+	invalid := [][]byte{
+		append([]byte{0x00, 0x01}, bytes.Repeat([]byte{0xff}, 20)...),
+		append([]byte{0x00, 0x02, 0x01, 0x00}, bytes.Repeat([]byte{0x41}, 20)...),
+		append([]byte{0x00, 0x02}, bytes.Repeat([]byte{0x01}, 20)...),
+	}
+
+	for _, block := range invalid {
+		if _, err := set.UnpadPKCS1v15(block); err == nil {
+			t.Errorf("Accepted %x", block)
+		}
+	}
+}
+
+func TestBleichenbacherAttack(t *testing.T) {
+
+	message := []byte("kick it, CC")
+
+	for _, bits := range []int{256, 768} {
+		if bits > 256 && testing.Short() {
+			continue
+		}
+
+		key, err := set5.GenerateKey(bits, big.NewInt(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ciphertext, err := set.EncryptPKCS1v15(&key.PublicKey, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		oracle := set.PaddingOracleFactory(key)
+		if !oracle(ciphertext) {
+			t.Fatalf("%d bits: oracle rejects a valid ciphertext", bits)
+		}
+
+		recovered, queries, err := set.BleichenbacherAttack(&key.PublicKey, ciphertext, oracle)
+		if err != nil {
+			t.Fatalf("%d bits: %v after %d queries", bits, err, queries)
+		}
+
+		plaintext, err := set.UnpadPKCS1v15(recovered.FillBytes(make([]byte, bits/8)))
+		if err != nil || !bytes.Equal(plaintext, message) {
+			t.Fatalf("%d bits: expected %q, got %x (%v)", bits, message, recovered, err)
+		}
+
+		t.Logf("%d bits: %d oracle queries", bits, queries)
+	}
+}
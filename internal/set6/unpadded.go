@@ -0,0 +1,131 @@
+package set
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	set5 "cryptopals/internal/set5"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+)
+
+type RSADecryptionHandler struct {
+	key  *set5.PrivateKey
+	mu   sync.Mutex
+	seen map[[sha256.Size]byte]bool
+}
+
+// decrypt takes the big endian ciphertext as body and answers with the
+// plaintext, every ciphertext is only decrypted once.
+func (rd *RSADecryptionHandler) decrypt(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ciphertext := new(big.Int).SetBytes(body)
+	// hash the canonical encoding such that leading zeros do not bypass the check
+	digest := sha256.Sum256(ciphertext.Bytes())
+
+	rd.mu.Lock()
+	seen := rd.seen[digest]
+	rd.seen[digest] = true
+	rd.mu.Unlock()
+
+	if seen {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	plaintext, err := rd.key.Decrypt(ciphertext)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/octet-stream")
+	if _, err := w.Write(plaintext.Bytes()); err != nil {
+		log.Print(err)
+	}
+}
+
+// CreateRSADecryptionHandler serves /decrypt for the given key
+func CreateRSADecryptionHandler(key *set5.PrivateKey) *http.ServeMux {
+
+	handler := RSADecryptionHandler{
+		key:  key,
+		seen: make(map[[sha256.Size]byte]bool),
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/decrypt", handler.decrypt)
+
+	return mux
+}
+
+// RequestDecryption posts the ciphertext to the /decrypt endpoint at baseURL
+func RequestDecryption(client *http.Client, baseURL string, ciphertext *big.Int) (*big.Int, error) {
+
+	resp, err := client.Post(baseURL+"/decrypt", "application/octet-stream", bytes.NewReader(ciphertext.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("decryption refused with status %d", resp.StatusCode)
+	}
+
+	plaintext, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(plaintext), nil
+}
+
+// RecoverUnpaddedMessage blinds the ciphertext as C' = S^e * C mod N, which
+// decrypts to P' = S * P mod N, and unblinds with S^-1.
+func RecoverUnpaddedMessage(client *http.Client, baseURL string, pub *set5.PublicKey, ciphertext *big.Int) (*big.Int, error) {
+
+	var (
+		s        *big.Int
+		sInverse *big.Int
+		err      error
+	)
+
+	// S needs to be invertible, which is practically always the case
+	for sInverse == nil {
+		s, err = rand.Int(rand.Reader, new(big.Int).Sub(pub.N, big.NewInt(2)))
+		if err != nil {
+			return nil, err
+		}
+		s.Add(s, big.NewInt(2))
+
+		sInverse, _ = set5.InvMod(s, pub.N)
+	}
+
+	blinded := set5.ModExp(s, pub.E, pub.N)
+	blinded.Mul(blinded, ciphertext)
+	blinded.Mod(blinded, pub.N)
+
+	decrypted, err := RequestDecryption(client, baseURL, blinded)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := decrypted.Mul(decrypted, sInverse)
+	return plaintext.Mod(plaintext, pub.N), nil
+}
@@ -0,0 +1,269 @@
+package set
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Transaction struct {
+	To     uint64 `json:"to"`
+	Amount uint64 `json:"amount"`
+}
+
+type Transfer struct {
+	From         uint64        `json:"from"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+var errMalformed = errors.New("malformed message")
+
+func parseAccount(s string) (uint64, error) {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, errMalformed
+		}
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func parseTransaction(to, amount string) (Transaction, error) {
+
+	t, err := parseAccount(to)
+	if err != nil {
+		return Transaction{}, errMalformed
+	}
+
+	a, err := parseAccount(amount)
+	if err != nil || a == 0 {
+		return Transaction{}, errMalformed
+	}
+
+	return Transaction{To: t, Amount: a}, nil
+}
+
+// ParseTransfer accepts exactly from=#&to=#&amount=# with decimal numbers
+func ParseTransfer(message []byte) (*Transfer, error) {
+
+	fields := strings.Split(string(message), "&")
+	if len(fields) != 3 ||
+		!strings.HasPrefix(fields[0], "from=") ||
+		!strings.HasPrefix(fields[1], "to=") ||
+		!strings.HasPrefix(fields[2], "amount=") {
+		return nil, errMalformed
+	}
+
+	from, err := parseAccount(strings.TrimPrefix(fields[0], "from="))
+	if err != nil {
+		return nil, errMalformed
+	}
+
+	tx, err := parseTransaction(strings.TrimPrefix(fields[1], "to="), strings.TrimPrefix(fields[2], "amount="))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transfer{From: from, Transactions: []Transaction{tx}}, nil
+}
+
+// ParseTransactionList accepts from=#&tx_list=#:#;#:#. The prefix and every
+// transaction are parsed strictly, but list entries that are not of the form
+// #:# at all are skipped: the PKCS#7 padding of an extended message and the
+// glue block always end up in the list, so no CBC-MAC length extension could
+// pass a parser that rejects them. At least one transaction is required.
+func ParseTransactionList(message []byte) (*Transfer, error) {
+
+	text := string(message)
+	if !strings.HasPrefix(text, "from=") {
+		return nil, errMalformed
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(text, "from="), "&tx_list=", 2)
+	if len(parts) != 2 {
+		return nil, errMalformed
+	}
+
+	from, err := parseAccount(parts[0])
+	if err != nil {
+		return nil, errMalformed
+	}
+
+	transfer := &Transfer{From: from}
+
+	for _, entry := range strings.Split(parts[1], ";") {
+		fields := strings.Split(entry, ":")
+		if len(fields) != 2 {
+			continue
+		}
+
+		if tx, err := parseTransaction(fields[0], fields[1]); err == nil {
+			transfer.Transactions = append(transfer.Transactions, tx)
+		}
+	}
+
+	if len(transfer.Transactions) == 0 {
+		return nil, errMalformed
+	}
+
+	return transfer, nil
+}
+
+type BankHandler struct {
+	key       []byte
+	mu        sync.Mutex
+	transfers []Transfer
+}
+
+func readBody(w http.ResponseWriter, r *http.Request, minLength int) ([]byte, bool) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) < minLength {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+
+	return body, true
+}
+
+func (b *BankHandler) execute(w http.ResponseWriter, transfer *Transfer) {
+
+	b.mu.Lock()
+	b.transfers = append(b.transfers, *transfer)
+	b.mu.Unlock()
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(transfer); err != nil {
+		log.Print(err)
+	}
+}
+
+// transfer takes message || IV || MAC with the IV chosen by the client
+func (b *BankHandler) transfer(w http.ResponseWriter, r *http.Request) {
+
+	body, ok := readBody(w, r, 2*blockSize+1)
+	if !ok {
+		return
+	}
+
+	n := len(body) - 2*blockSize
+	message, iv, mac := body[:n], body[n:n+blockSize], body[n+blockSize:]
+
+	if !VerifyCBCMAC(message, b.key, iv, mac) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	transfer, err := ParseTransfer(message)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	b.execute(w, transfer)
+}
+
+// transactionList takes message || MAC under a zero IV
+func (b *BankHandler) transactionList(w http.ResponseWriter, r *http.Request) {
+
+	body, ok := readBody(w, r, blockSize+1)
+	if !ok {
+		return
+	}
+
+	n := len(body) - blockSize
+	message, mac := body[:n], body[n:]
+
+	if !VerifyCBCMAC(message, b.key, make([]byte, blockSize), mac) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	transfer, err := ParseTransactionList(message)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	b.execute(w, transfer)
+}
+
+// CreateBankHandler serves /v1/transfer and /v2/transfer for clients sharing
+// the key, executed transfers are answered as JSON.
+func CreateBankHandler(key []byte) *http.ServeMux {
+
+	handler := &BankHandler{key: key}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/transfer", handler.transfer)
+	mux.HandleFunc("/v2/transfer", handler.transactionList)
+
+	return mux
+}
+
+// BankClient signs messages for its own account only
+type BankClient struct {
+	Key     []byte
+	Account uint64
+}
+
+// Transfer returns the body for /v1/transfer with a random IV
+func (c *BankClient) Transfer(to, amount uint64) []byte {
+
+	message := []byte(fmt.Sprintf("from=%d&to=%d&amount=%d", c.Account, to, amount))
+
+	iv := make([]byte, blockSize)
+	if _, err := rand.Reader.Read(iv); err != nil {
+		panic("Not enough randomness")
+	}
+
+	body := append(message, iv...)
+	return append(body, CBCMAC(message, c.Key, iv)...)
+}
+
+// TransactionList returns the body for /v2/transfer
+func (c *BankClient) TransactionList(transactions []Transaction) []byte {
+
+	list := make([]string, len(transactions))
+	for i, tx := range transactions {
+		list[i] = fmt.Sprintf("%d:%d", tx.To, tx.Amount)
+	}
+
+	message := []byte(fmt.Sprintf("from=%d&tx_list=%s", c.Account, strings.Join(list, ";")))
+
+	return append(message, CBCMAC(message, c.Key, make([]byte, blockSize))...)
+}
+
+// SubmitTransfer posts a signed body to the path at baseURL
+func SubmitTransfer(client *http.Client, url string, body []byte) (*Transfer, error) {
+
+	resp, err := client.Post(url, "application/octet-stream", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transfer refused with status %d", resp.StatusCode)
+	}
+
+	var transfer Transfer
+	if err := json.NewDecoder(resp.Body).Decode(&transfer); err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
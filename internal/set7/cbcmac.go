@@ -0,0 +1,19 @@
+package set
+
+import (
+	"crypto/subtle"
+	set2 "cryptopals/internal/set2"
+)
+
+const blockSize = 16
+
+// CBCMAC is the last block of the AES-CBC encryption of the PKCS#7 padded message
+func CBCMAC(message, key, iv []byte) []byte {
+	// copy since padding appends to the input
+	ciphertext := set2.CBCEncryptWithIV(append([]byte{}, message...), key, iv)
+	return ciphertext[len(ciphertext)-blockSize:]
+}
+
+func VerifyCBCMAC(message, key, iv, mac []byte) bool {
+	return subtle.ConstantTimeCompare(CBCMAC(message, key, iv), mac) == 1
+}
@@ -0,0 +1,70 @@
+package set
+
+import (
+	"bytes"
+	"crypto/aes"
+	set2 "cryptopals/internal/set2"
+	"errors"
+	"fmt"
+)
+
+var (
+	hashKey = []byte("YELLOW SUBMARINE")
+	hashIV  = make([]byte, blockSize)
+)
+
+// CBCMACHash uses CBC-MAC with the public key YELLOW SUBMARINE and a zero IV
+// as hash function, which is not collision resistant since the key is known.
+func CBCMACHash(message []byte) []byte {
+	return CBCMAC(message, hashKey, hashIV)
+}
+
+// ForgeCBCMACHash returns prefix || padding || glue with the given hash.
+// The hash of a block aligned message is E(s xor 10..10) for the state s
+// after the glue block, so s is D(target) xor 10..10 and the glue block is
+// D(s) xor the hash of the prefix.
+func ForgeCBCMACHash(prefix, target []byte) []byte {
+
+	if len(target) != blockSize {
+		panic(fmt.Sprintf("Hash size of %d does not match block size %d", len(target), blockSize))
+	}
+
+	c, err := aes.NewCipher(hashKey)
+	if err != nil {
+		panic(fmt.Sprintf("Could not create AES-Cipher with key [%x] (%d)", hashKey, len(hashKey)))
+	}
+
+	state := make([]byte, blockSize)
+	c.Decrypt(state, target)
+	state = set2.XOR(state, bytes.Repeat([]byte{blockSize}, blockSize))
+
+	glue := make([]byte, blockSize)
+	c.Decrypt(glue, state)
+	glue = set2.XOR(glue, CBCMACHash(prefix))
+
+	forged := set2.PCKS7PaddingVarBlockLen(append([]byte{}, prefix...), blockSize)
+	return append(forged, glue...)
+}
+
+const maxSnippetAttempts = 1024
+
+// ForgeJavaScript turns snippet into a script with the given hash: padding
+// and glue are hidden behind a line comment, which is extended by spaces until
+// neither contains a line break that would end the comment. 0xe2 is avoided
+// as well since it starts the UTF-8 encoding of U+2028 and U+2029.
+func ForgeJavaScript(snippet string, target []byte) ([]byte, error) {
+
+	prefix := []byte(snippet + "\n//")
+
+	for attempt := 0; attempt < maxSnippetAttempts; attempt++ {
+		forged := ForgeCBCMACHash(prefix, target)
+
+		if !bytes.ContainsAny(forged[len(prefix):], "\n\r") && bytes.IndexByte(forged[len(prefix):], 0xe2) < 0 {
+			return forged, nil
+		}
+
+		prefix = append(prefix, ' ')
+	}
+
+	return nil, errors.New("no glue without line breaks found")
+}
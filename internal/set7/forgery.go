@@ -0,0 +1,86 @@
+package set
+
+import (
+	"bytes"
+	set2 "cryptopals/internal/set2"
+	"errors"
+	"fmt"
+)
+
+// ForgeFromAccount rewrites the from account of a /v1/transfer body. The first
+// block of CBC is P1 xor IV, so flipping bits of the IV flips the same bits of
+// P1 without changing the MAC. Both accounts need the same number of digits.
+func ForgeFromAccount(body []byte, victim uint64) ([]byte, error) {
+
+	if len(body) < 2*blockSize+1 {
+		return nil, errors.New("body too short")
+	}
+
+	n := len(body) - 2*blockSize
+	message, iv, mac := body[:n], body[n:n+blockSize], body[n+blockSize:]
+
+	transfer, err := ParseTransfer(message)
+	if err != nil {
+		return nil, err
+	}
+
+	original := []byte(fmt.Sprintf("from=%d", transfer.From))
+	forged := []byte(fmt.Sprintf("from=%d", victim))
+
+	if len(forged) != len(original) {
+		return nil, errors.New("accounts differ in length")
+	}
+
+	if len(forged) > blockSize {
+		return nil, errors.New("from account exceeds the first block")
+	}
+
+	newMessage := append(forged, message[len(original):]...)
+
+	newIV := append([]byte{}, iv...)
+	for i := range forged {
+		newIV[i] ^= original[i] ^ forged[i]
+	}
+
+	result := append(newMessage, newIV...)
+	return append(result, mac...), nil
+}
+
+// ExtendTransactionList glues the attacker's signed /v2/transfer body to the
+// victim's. After the padded victim message the CBC state is the victim's
+// MAC, so xoring it into the first block of the attacker's message continues
+// exactly like the attacker's message from a zero IV and the attacker's MAC
+// is valid for the result. The victim's padding and the first attacker block
+// turn into garbage entries, which ParseTransactionList skips.
+func ExtendTransactionList(victim, attacker []byte) ([]byte, error) {
+
+	if len(victim) < blockSize+1 || len(attacker) < 2*blockSize+1 {
+		return nil, errors.New("body too short")
+	}
+
+	victimMessage, victimMAC := victim[:len(victim)-blockSize], victim[len(victim)-blockSize:]
+	attackerMessage := attacker[:len(attacker)-blockSize]
+
+	forged := set2.PCKS7PaddingVarBlockLen(append([]byte{}, victimMessage...), blockSize)
+	forged = append(forged, set2.XOR(attackerMessage[:blockSize], victimMAC)...)
+	forged = append(forged, attacker[blockSize:]...)
+
+	return forged, nil
+}
+
+// TransactionListForgery signs a list with a throwaway payment of 1 first,
+// whose start ends up in the garbage block, and glues it to the victim's body.
+func TransactionListForgery(victim []byte, attacker *BankClient, amount uint64) ([]byte, error) {
+
+	signed := attacker.TransactionList([]Transaction{
+		{To: attacker.Account, Amount: 1},
+		{To: attacker.Account, Amount: amount},
+	})
+
+	// the real payment needs to start after the first block
+	if bytes.Index(signed, []byte(fmt.Sprintf(";%d:%d", attacker.Account, amount))) < blockSize {
+		return nil, errors.New("payment starts within the first block")
+	}
+
+	return ExtendTransactionList(victim, signed)
+}
@@ -0,0 +1,237 @@
+package set_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	set "cryptopals/internal/set7"
+	"encoding/hex"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func randomKey(t *testing.T) []byte {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestCBCMAC(t *testing.T) {
+
+	key := randomKey(t)
+	iv := make([]byte, 16)
+	message := []byte("from=1&to=2&amount=3")
+
+	mac := set.CBCMAC(message, key, iv)
+	if len(mac) != 16 {
+		t.Fatalf("Expected 16 bytes, got %d", len(mac))
+	}
+
+	if !set.VerifyCBCMAC(message, key, iv, mac) {
+		t.Error("Valid MAC rejected")
+	}
+
+	if set.VerifyCBCMAC([]byte("from=1&to=2&amount=4"), key, iv, mac) {
+		t.Error("MAC accepted for another message")
+	}
+
+	// the message must not be modified by padding
+	backing := []byte("from=1&to=2&amount=3xxxxxxxxxxxx")
+	set.CBCMAC(backing[:20], key, iv)
+	if !bytes.Equal(backing[20:], []byte("xxxxxxxxxxxx")) {
+		t.Errorf("CBCMAC wrote past the message: %q", backing)
+	}
+}
+
+func TestParseTransfer(t *testing.T) {
+
+	tests := []struct {
+		message string
+		want    *set.Transfer
+	}{
+		{message: "from=1&to=2&amount=3", want: &set.Transfer{From: 1, Transactions: []set.Transaction{{To: 2, Amount: 3}}}},
+		{message: "from=1&to=2&amount=0"},
+		{message: "from=1&to=2&amount=+3"},
+		{message: "from=1&amount=3&to=2"},
+		{message: "from=1&to=2&amount=3&to=4"},
+		{message: "from=a&to=2&amount=3"},
+		{message: "from=&to=2&amount=3"},
+	}
+
+	for _, tt := range tests {
+		got, err := set.ParseTransfer([]byte(tt.message))
+		if (err != nil) != (tt.want == nil) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTransfer(%q) = %v, %v", tt.message, got, err)
+		}
+	}
+}
+
+func TestParseTransactionList(t *testing.T) {
+
+	got, err := set.ParseTransactionList([]byte("from=1&tx_list=2:3;4:5\x01\x02;6:7"))
+	want := &set.Transfer{From: 1, Transactions: []set.Transaction{{To: 2, Amount: 3}, {To: 6, Amount: 7}}}
+
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v (%v)", want, got, err)
+	}
+
+	for _, message := range []string{
+		"from=1&tx_list=",
+		"from=1&tx_list=2:x",
+		"from=1&tx_list=2:3:4",
+		"from=1&tx_list=\x01\x02;x:y",
+		"tx_list=2:3",
+		"from=x&tx_list=2:3",
+		"from=1&to=2&amount=3",
+	} {
+		if transfer, err := set.ParseTransactionList([]byte(message)); err == nil {
+			t.Errorf("Accepted %q as %v", message, transfer)
+		}
+	}
+}
+
+func TestForgeFromAccount(t *testing.T) {
+
+	key := randomKey(t)
+	server := httptest.NewServer(set.CreateBankHandler(key))
+	defer server.Close()
+
+	url := server.URL + "/v1/transfer"
+	attacker := &set.BankClient{Key: key, Account: 1337}
+
+	body := attacker.Transfer(1337, 1000000)
+
+	if transfer, err := set.SubmitTransfer(server.Client(), url, body); err != nil || transfer.From != 1337 {
+		t.Fatalf("Legitimate transfer failed: %v (%v)", transfer, err)
+	}
+
+	forged, err := set.ForgeFromAccount(body, 4242)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfer, err := set.SubmitTransfer(server.Client(), url, forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &set.Transfer{From: 4242, Transactions: []set.Transaction{{To: 1337, Amount: 1000000}}}
+	if !reflect.DeepEqual(transfer, want) {
+		t.Fatalf("Expected %v, got %v", want, transfer)
+	}
+
+	if _, err := set.ForgeFromAccount(body, 42); err == nil {
+		t.Error("Forged an account of different length")
+	}
+
+	// the MAC still protects everything but the first block
+	tampered := append([]byte{}, forged...)
+	tampered[len("from=4242&to=1337&amount=1")] ^= 1
+	if _, err := set.SubmitTransfer(server.Client(), url, tampered); err == nil {
+		t.Error("Server accepted a tampered body")
+	}
+}
+
+func TestTransactionListForgery(t *testing.T) {
+
+	key := randomKey(t)
+	server := httptest.NewServer(set.CreateBankHandler(key))
+	defer server.Close()
+
+	url := server.URL + "/v2/transfer"
+	victim := &set.BankClient{Key: key, Account: 4242}
+	attacker := &set.BankClient{Key: key, Account: 1337}
+
+	// captured from the victim
+	captured := victim.TransactionList([]set.Transaction{{To: 7, Amount: 100}, {To: 8, Amount: 250}})
+
+	if _, err := set.SubmitTransfer(server.Client(), url, captured); err != nil {
+		t.Fatalf("Legitimate transfer failed: %v", err)
+	}
+
+	forged, err := set.TransactionListForgery(captured, attacker, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, mac := forged[:len(forged)-16], forged[len(forged)-16:]
+
+	if !bytes.HasPrefix(message, captured[:len(captured)-16]) || !bytes.HasSuffix(message, []byte(";1337:1000000")) {
+		t.Fatalf("Unexpected forgery %q", message)
+	}
+
+	if !set.VerifyCBCMAC(message, key, make([]byte, 16), mac) {
+		t.Fatal("Forged MAC is invalid")
+	}
+
+	transfer, err := set.SubmitTransfer(server.Client(), url, forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transfer.From != 4242 {
+		t.Fatalf("Expected the victim's account, got %d", transfer.From)
+	}
+
+	want := set.Transaction{To: 1337, Amount: 1000000}
+	if last := transfer.Transactions[len(transfer.Transactions)-1]; last != want {
+		t.Fatalf("Expected %v last, got %v", want, transfer.Transactions)
+	}
+
+	// the fixed IV endpoint does not accept v1 bodies and vice versa
+	if _, err := set.SubmitTransfer(server.Client(), server.URL+"/v1/transfer", forged); err == nil {
+		t.Error("v1 accepted a v2 body")
+	}
+
+	if _, err := set.SubmitTransfer(server.Client(), url, victim.Transfer(7, 100)); err == nil {
+		t.Error("v2 accepted a v1 body")
+	}
+}
+
+func TestCBCMACHash(t *testing.T) {
+
+	original := []byte("alert('MZA who was that?');\n")
+	hash := set.CBCMACHash(original)
+
+	if hex.EncodeToString(hash) != "296b8d7cb78a243dda4d0a61d33bbdd1" {
+		t.Fatalf("Unexpected hash %x", hash)
+	}
+
+	prefix := []byte("arbitrary chosen content")
+	forged := set.ForgeCBCMACHash(prefix, hash)
+
+	if !bytes.HasPrefix(forged, prefix) || len(forged)%16 != 0 {
+		t.Fatalf("Unexpected forgery %q", forged)
+	}
+
+	if got := set.CBCMACHash(forged); !bytes.Equal(got, hash) {
+		t.Fatalf("Expected %x, got %x", hash, got)
+	}
+}
+
+func TestForgeJavaScript(t *testing.T) {
+
+	original := []byte("alert('MZA who was that?');\n")
+	hash := set.CBCMACHash(original)
+
+	snippet := "alert('Ayo, the Wu is back!');"
+	forged, err := set.ForgeJavaScript(snippet, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := set.CBCMACHash(forged); !bytes.Equal(got, hash) {
+		t.Fatalf("Expected %x, got %x", hash, got)
+	}
+
+	if !bytes.HasPrefix(forged, []byte(snippet+"\n//")) {
+		t.Fatalf("Snippet missing in %q", forged)
+	}
+
+	// everything after the snippet has to stay in the comment
+	if comment := forged[len(snippet)+1:]; bytes.ContainsAny(comment, "\n\r") {
+		t.Fatalf("Line break in comment %q", comment)
+	}
+}